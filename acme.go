@@ -0,0 +1,132 @@
+package socle
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/socle-framework/cache"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// initACME creates the certificate manager when an entry uses the "le" strategy.
+// Certificates are issued and renewed from ACME_DIRECTORY_URL (Let's Encrypt by default)
+// for ACME_DOMAINS, and kept in the configured cache or under tmp/certs, depending on ACME_CACHE.
+func (s *Socle) initACME() error {
+	le := false
+	for _, e := range s.Entries {
+		if e.Server.Secure && e.Server.Security.Strategy == "le" {
+			le = true
+		}
+	}
+	if !le {
+		return nil
+	}
+
+	cfg := s.env.acme
+	domains := cfg.domains
+	if len(domains) == 0 {
+		domains = []string{s.env.serverName}
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Email:      cfg.email,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Client:     &acme.Client{DirectoryURL: cfg.directoryURL},
+	}
+
+	// a private ACME server, such as pebble, is served with a certificate of its own CA
+	if cfg.caRoots != "" {
+		caBytes, err := os.ReadFile(cfg.caRoots)
+		if err != nil {
+			return err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caBytes) {
+			return fmt.Errorf("acme: no certificate found in %s", cfg.caRoots)
+		}
+		m.Client.HTTPClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots},
+			},
+		}
+	}
+
+	switch cfg.cache {
+	case "cache":
+		if s.Cache == nil {
+			return fmt.Errorf("acme: ACME_CACHE=cache requires a cache")
+		}
+		m.Cache = acmeCache{s.Cache}
+	default:
+		m.Cache = autocert.DirCache(s.RootPath + "/tmp/certs")
+	}
+
+	s.acme = m
+	return nil
+}
+
+// auxServers returns the servers run next to the entries, such as the ACME challenge
+// server and the metrics admin server.
+func (s *Socle) auxServers() []*http.Server {
+	var servers []*http.Server
+	if srv := s.acmeChallengeServer(); srv != nil {
+		servers = append(servers, srv)
+	}
+	if srv := s.metricsServer(); srv != nil {
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+// acmeChallengeServer returns the server answering the HTTP-01 challenges on ACME_HTTP_PORT,
+// which redirects the other requests to https.
+func (s *Socle) acmeChallengeServer() *http.Server {
+	if s.acme == nil || s.env.acme.httpPort == "" {
+		return nil
+	}
+	return &http.Server{
+		Addr:              ":" + s.env.acme.httpPort,
+		ErrorLog:          s.Log.ErrorLog,
+		Handler:           s.acme.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// acmeCache stores the certificates and the account key of the ACME manager in the cache,
+// so that the instances sharing a redis cache share their certificates.
+type acmeCache struct {
+	c cache.Cache
+}
+
+func (a acmeCache) key(name string) string {
+	return "acme:" + name
+}
+
+func (a acmeCache) Get(ctx context.Context, name string) ([]byte, error) {
+	value, err := a.c.Get(a.key(name))
+	if err != nil || value == nil {
+		return nil, autocert.ErrCacheMiss
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, autocert.ErrCacheMiss
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (a acmeCache) Put(ctx context.Context, name string, data []byte) error {
+	return a.c.Set(a.key(name), base64.StdEncoding.EncodeToString(data))
+}
+
+func (a acmeCache) Delete(ctx context.Context, name string) error {
+	return a.c.Forget(a.key(name))
+}
@@ -3,6 +3,7 @@ package socle
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,8 +21,52 @@ type appConfig struct {
 		Render string `yaml:"render"`
 	} `yaml:"defaults"`
 
-	Modules []string `yaml:"modules"`
-	Entries entries  `yaml:"entries"`
+	Modules   []string        `yaml:"modules"`
+	Entries   entries         `yaml:"entries"`
+	Tracing   tracingConfig   `yaml:"tracing"`
+	OAuth     oauthConfig     `yaml:"oauth"`
+	RateLimit rateLimitConfig `yaml:"rate_limit"`
+}
+
+type rateLimitConfig struct {
+	Key    string           `yaml:"key"`    // ip, api_key, subject or auto (default)
+	Groups []rateLimitGroup `yaml:"groups"` // limits of the route groups
+}
+
+type rateLimitGroup struct {
+	Path     string        `yaml:"path"` // prefix of the routes of the group
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"` // 30s, 1m, 1h...
+	Key      string        `yaml:"key"`    // defaults to the key of rate_limit
+}
+
+type oauthConfig struct {
+	Path         string                         `yaml:"path"`          // defaults to /auth
+	RedirectBase string                         `yaml:"redirect_base"` // public url of the web entry
+	AfterLogin   string                         `yaml:"after_login"`   // defaults to /
+	LoginPage    string                         `yaml:"login_page"`    // on failure, defaults to /users/login
+	Providers    map[string]oauthProviderConfig `yaml:"providers"`     // google, github or any name
+}
+
+type oauthProviderConfig struct {
+	Issuer       string   `yaml:"issuer"` // OIDC providers, discovered
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+	AuthURL      string   `yaml:"auth_url"` // plain OAuth2 providers
+	TokenURL     string   `yaml:"token_url"`
+	UserInfoURL  string   `yaml:"userinfo_url"`
+	TrustEmail   bool     `yaml:"trust_email"` // link the verified emails to the existing users
+}
+
+type tracingConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	ServiceName string   `yaml:"service_name"` // defaults to name
+	Exporter    string   `yaml:"exporter"`     // otlp, stdout, file
+	Endpoint    string   `yaml:"endpoint"`     // otlp collector, host:port
+	Insecure    bool     `yaml:"insecure"`
+	File        string   `yaml:"file"`         // file exporter, defaults to tmp/traces.json
+	SampleRatio *float64 `yaml:"sample_ratio"` // defaults to 1
 }
 
 type server struct {
@@ -65,7 +110,7 @@ type apiEntry struct {
 	Security    securityConfig           `yaml:"security"`
 	Multiple    bool                     `yaml:"multiple"`
 	Type        string                   `yaml:"type"`
-	Types       map[string]apiTypeConfig `yaml:"types"` // rest, graphql, rpc
+	Types       map[string]apiTypeConfig `yaml:"types"` // rest, graphql, grpc
 }
 
 type webEntry struct {
@@ -0,0 +1,366 @@
+package socle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/socle/pkg/auth"
+)
+
+// minTokenSecret is the length of AUTH_TOKEN_SECRET required unless MODE=dev, that of the
+// output of SHA-256.
+const minTokenSecret = 32
+
+// initAuthentificator creates the Authenticator of the api entries. It signs the tokens
+// with the first key of AUTH_TOKEN_KEYS (kid=file.pem,...), or with AUTH_TOKEN_SECRET
+// (HS256), and also verifies the tokens signed by the keys of the JWKS at AUTH_JWKS_URL.
+// Without AUTH_TOKEN_KEYS, a secret shorter than 32 bytes is refused unless MODE=dev.
+func (s *Socle) initAuthentificator() error {
+	if !s.runs("api/rest") && !s.runs("api/graphql") && !s.runs("api/grpc") {
+		return nil
+	}
+
+	cfg := s.env.auth.token
+	if len(cfg.keys) == 0 && len(cfg.secret) < minTokenSecret {
+		if !s.devMode() {
+			return fmt.Errorf("auth: AUTH_TOKEN_SECRET must hold at least %d bytes, or set AUTH_TOKEN_KEYS", minTokenSecret)
+		}
+		s.Logger.Warn("auth: AUTH_TOKEN_SECRET is empty or short, the tokens can be forged", "min_bytes", minTokenSecret)
+	}
+	keys := auth.NewKeySet(auth.NewHMACKey("", []byte(cfg.secret)))
+	if len(cfg.keys) > 0 {
+		loaded := make([]*auth.Key, 0, len(cfg.keys))
+		for _, item := range cfg.keys {
+			kid, path, found := strings.Cut(item, "=")
+			if !found {
+				return fmt.Errorf("AUTH_TOKEN_KEYS: %q is not kid=path", item)
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(s.RootPath, path)
+			}
+			key, err := auth.LoadKeyFile(kid, path)
+			if err != nil {
+				return err
+			}
+			loaded = append(loaded, key)
+		}
+		keys = auth.NewKeySet(loaded[0], loaded[1:]...)
+	}
+
+	var remote []auth.KeyResolver
+	if cfg.jwksURL != "" {
+		source := cfg.jwksURL
+		if !strings.Contains(source, "://") && !filepath.IsAbs(source) {
+			source = filepath.Join(s.RootPath, source)
+		}
+		remote = append(remote, auth.NewRemoteKeySet(source, cfg.jwksRefresh))
+	}
+
+	authenticator := auth.NewJWTAuthenticatorWithKeys(keys, cfg.aud, cfg.iss, remote...)
+	store, err := s.tokenStore()
+	if err != nil {
+		return err
+	}
+	authenticator.Store = store
+	s.Authenticator = authenticator
+	s.authKeys = keys
+
+	// publish the public keys, for the other services to verify our tokens
+	if len(keys.JWKS().Keys) > 0 && cfg.jwksPath != "" {
+		for _, e := range s.Entries {
+			if e.Routes != nil {
+				e.mountPublic(func(r chi.Router) {
+					r.Get(cfg.jwksPath, s.JWKSHandler)
+				})
+			}
+		}
+	}
+
+	if store != nil {
+		for _, e := range s.Entries {
+			if e.Routes != nil && strings.HasPrefix(e.Name, "api/") {
+				// the access token may have expired, the refresh token authenticates these requests
+				e.mountPublic(func(r chi.Router) {
+					r.Post(cfg.refreshPath, s.RefreshTokenHandler)
+					r.Post(cfg.revokePath, s.RevokeTokenHandler)
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// tokenStore returns the store of the refresh tokens and revocations chosen by
+// AUTH_TOKEN_STORE. When AUTH_TOKEN_STORE is empty, the tokens are kept in redis or
+// badger if a cache is configured, or in the database. Without any, tokens cannot be
+// revoked and nil is returned.
+func (s *Socle) tokenStore() (auth.TokenStore, error) {
+	kind := s.env.auth.token.store
+	if kind == "" {
+		switch {
+		case redisPool != nil:
+			kind = "redis"
+		case badgerConn != nil:
+			kind = "badger"
+		case s.DB.Pool != nil:
+			kind = "sql"
+		default:
+			return nil, nil
+		}
+	}
+
+	switch kind {
+	case "redis":
+		if redisPool == nil {
+			redisPool = s.createRedisPool()
+		}
+		return auth.NewRedisStore(redisPool, s.redisPrefix()), nil
+	case "badger":
+		if badgerConn == nil {
+			badgerConn = s.createBadgerConn()
+		}
+		if badgerConn == nil {
+			return nil, errors.New("auth: unable to open badger database")
+		}
+		return auth.NewBadgerStore(badgerConn), nil
+	case "sql", "postgres", "mysql", "mariadb":
+		if s.DB.Pool == nil {
+			return nil, errors.New("auth: the sql token store requires a database connection")
+		}
+		store := auth.NewSQLStore(s.DB.Pool, s.DB.DBType)
+		_, err := s.Schedule("auth_tokens_prune", "@hourly", func() {
+			if err := store.Prune(context.Background()); err != nil {
+				s.Log.ErrorLog.Println("auth: pruning tokens:", err)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, errors.New("auth: unknown token store " + kind)
+}
+
+// refresher returns the Authenticator when it rotates refresh tokens and revokes tokens,
+// which the JWTAuthenticator does only with a token store.
+func (s *Socle) refresher() (auth.RefreshAuthenticator, bool) {
+	ra, ok := s.Authenticator.(auth.RefreshAuthenticator)
+	if a, isJWT := ra.(*auth.JWTAuthenticator); isJWT && a.Store == nil {
+		return nil, false
+	}
+	return ra, ok
+}
+
+// RefreshTokenHandler exchanges the refresh token posted as {"refresh_token": "..."} for
+// a new access token and a new refresh token. A refresh token already used is refused,
+// and revokes the tokens rotated from the same login.
+func (s *Socle) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ra, ok := s.refresher()
+	if !ok {
+		s.Error404(w, r)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := s.ReadJSON(w, r, &req); err != nil || req.RefreshToken == "" {
+		s.errorResponse(w, r, http.StatusBadRequest)
+		return
+	}
+
+	cfg := s.env.auth.token
+	pair, err := ra.Refresh(r.Context(), req.RefreshToken, cfg.exp, cfg.refresh, cfg.iss)
+	if err != nil {
+		if errors.Is(err, auth.ErrTokenReused) {
+			s.Log.InfoLog.Println("auth: refresh token reused, its family is revoked")
+		} else {
+			s.Log.DebugLog.Println("auth: refresh refused:", err)
+		}
+		w.Header().Set("WWW-Authenticate", s.bearerChallenge("invalid_token"))
+		s.ErrorUnauthorized(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	_ = s.WriteJSON(w, http.StatusOK, pair)
+}
+
+// RevokeTokenHandler revokes the token posted as {"token": "..."}: an access token, or
+// the family of a refresh token. With {"all": true}, it logs out all the sessions of the
+// user authenticated by the bearer token of the request. As in RFC 7009, an invalid token
+// is not an error.
+func (s *Socle) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ra, ok := s.refresher()
+	if !ok {
+		s.Error404(w, r)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+		All   bool   `json:"all"`
+	}
+	if err := s.ReadJSON(w, r, &req); err != nil || (req.Token == "" && !req.All) {
+		s.errorResponse(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if req.All {
+		payload, err := s.authenticate(r)
+		if err != nil || payload == nil {
+			w.Header().Set("WWW-Authenticate", s.bearerChallenge(""))
+			s.ErrorUnauthorized(w, r)
+			return
+		}
+		if err := s.LogoutAll(r.Context(), payload.Username); err != nil {
+			s.Log.ErrorLog.Println("auth: revoking the tokens of", payload.Username, ":", err)
+			s.Error500(w, r)
+			return
+		}
+	}
+
+	if req.Token != "" {
+		err := ra.Revoke(r.Context(), req.Token)
+		if err != nil && !errors.Is(err, auth.ErrInvalidToken) {
+			s.Log.ErrorLog.Println("auth: revoking token:", err)
+			s.Error500(w, r)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// LogoutAll revokes all the tokens issued to username so far, access and refresh ones.
+func (s *Socle) LogoutAll(ctx context.Context, username string) error {
+	ra, ok := s.refresher()
+	if !ok {
+		return auth.ErrNoTokenStore
+	}
+	return ra.RevokeUser(ctx, username, max(s.env.auth.token.exp, s.env.auth.token.refresh))
+}
+
+// JWKSHandler serves the public keys verifying the tokens of the Authenticator.
+func (s *Socle) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if s.authKeys == nil {
+		s.Error404(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = s.WriteJSON(w, http.StatusOK, s.authKeys.JWKS())
+}
+
+// authenticate validates the bearer token of r. It returns a nil payload without error
+// when r has no token.
+func (s *Socle) authenticate(r *http.Request) (*auth.Payload, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, nil
+	}
+	if s.Authenticator == nil {
+		return nil, auth.ErrInvalidToken
+	}
+	return s.Authenticator.ValidateToken(token)
+}
+
+// bearerChallenge returns the WWW-Authenticate header of the 401 responses (RFC 6750).
+func (s *Socle) bearerChallenge(code string) string {
+	challenge := "Bearer"
+	if s.AppName != "" {
+		challenge += ` realm="` + s.AppName + `"`
+	}
+	if code != "" {
+		if challenge != "Bearer" {
+			challenge += ","
+		}
+		challenge += ` error="` + code + `"`
+	}
+	return challenge
+}
+
+// AuthMiddleware rejects with 401 the requests without a valid bearer token, and stores
+// the payload of the token in the context of the others, see auth.FromContext.
+func (s *Socle) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := s.authenticate(r)
+		if err != nil || payload == nil {
+			s.Log.DebugLog.Println("auth middleware: rejected request:", err)
+			w.Header().Set("WWW-Authenticate", s.bearerChallenge(""))
+			s.ErrorUnauthorized(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPayload(r.Context(), payload)))
+	})
+}
+
+// OptionalAuthMiddleware stores the payload of the bearer token in the context when the
+// request has one, and lets anonymous requests through. Invalid tokens are still rejected.
+func (s *Socle) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", s.bearerChallenge("invalid_token"))
+			s.ErrorUnauthorized(w, r)
+			return
+		}
+		if payload != nil {
+			r = r.WithContext(withPayload(r.Context(), payload))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRoles returns a middleware letting through the requests authenticated with a
+// token granting one of roles. It goes after the auth middleware, on a route group:
+//
+//	r.With(s.RequireRoles("admin")).Delete("/users/{id}", h.DeleteUser)
+func (s *Socle) RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return s.require(func(p *auth.Payload) bool {
+		for _, role := range roles {
+			if p.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequireScopes returns a middleware letting through the requests authenticated with a
+// token granting all of scopes.
+func (s *Socle) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return s.require(func(p *auth.Payload) bool {
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// require answers 401 to anonymous requests and 403 to those whose payload fails allowed.
+func (s *Socle) require(allowed func(*auth.Payload) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload, ok := auth.FromContext(r.Context())
+			if !ok {
+				s.ErrorUnauthorized(w, r)
+				return
+			}
+			if !allowed(payload) {
+				s.ErrorForbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
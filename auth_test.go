@@ -0,0 +1,69 @@
+package socle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitAuthentificatorSecret(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "jwt.pem"), pemKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		keys   []string
+		mode   string
+		ok     bool
+	}{
+		{name: "empty", secret: "", ok: false},
+		{name: "short", secret: "changeme", ok: false},
+		{name: "31 bytes", secret: strings.Repeat("s", 31), ok: false},
+		{name: "32 bytes", secret: strings.Repeat("s", 32), ok: true},
+		{name: "empty in production mode", secret: "", mode: "production", ok: false},
+		{name: "short in dev mode", secret: "changeme", mode: "dev", ok: true},
+		{name: "keys without secret", keys: []string{"k1=jwt.pem"}, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Socle{
+				RootPath: dir,
+				Debug:    true, // the default of DEBUG, which does not accept the insecure defaults
+				Entries:  []*Entry{{Name: "api/rest"}},
+				Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			s.env.mode = tt.mode
+			s.env.auth.token.secret = tt.secret
+			s.env.auth.token.keys = tt.keys
+			s.env.auth.token.store = "none"
+
+			err := s.initAuthentificator()
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok = %v", err, tt.ok)
+			}
+			if tt.ok && s.Authenticator == nil {
+				t.Error("no Authenticator")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package socle
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/socle-framework/socle/pkg/auth"
+)
+
+// initBasicAuth loads the users of the basic_auth middleware from AUTH_BASIC_FILE, or
+// uses the single AUTH_BASIC_USER and AUTH_BASIC_PASS. The admin/admin default is refused
+// unless MODE=dev: New fails when an entry uses basic_auth, and the routes protected with
+// BasicAuth refuse every request.
+func (s *Socle) initBasicAuth() error {
+	cfg := s.env.auth.basic
+	if cfg.file != "" {
+		path := cfg.file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.RootPath, path)
+		}
+		users, err := auth.LoadBasicUsers(path)
+		if err != nil {
+			return err
+		}
+		s.basicUsers = users
+		return nil
+	}
+
+	if cfg.user != "admin" || cfg.pass != "admin" {
+		return nil
+	}
+	if s.devMode() {
+		s.Logger.Warn("basic auth: using the default admin/admin credentials, set AUTH_BASIC_USER and AUTH_BASIC_PASS or AUTH_BASIC_FILE")
+		return nil
+	}
+
+	s.basicInsecure = true
+	for _, e := range s.Entries {
+		if InArrayStr("basic_auth", e.Server.Middlewares) {
+			return errors.New("basic auth: refusing the default admin/admin credentials outside of MODE=dev, set AUTH_BASIC_USER and AUTH_BASIC_PASS or AUTH_BASIC_FILE")
+		}
+	}
+	return nil
+}
+
+// BasicAuthMiddleware asks for the credentials of HTTP Basic authentication, in the realm
+// of AUTH_BASIC_REALM.
+func (s *Socle) BasicAuthMiddleware(next http.Handler) http.Handler {
+	return s.BasicAuth(s.env.auth.basic.realm)(next)
+}
+
+// BasicAuth returns a middleware asking for the credentials of HTTP Basic authentication
+// in realm, to protect some routes only:
+//
+//	r.With(s.BasicAuth("metrics")).Get("/internal/stats", h.Stats)
+//
+// The username is stored in the context, see auth.Username.
+func (s *Socle) BasicAuth(realm string) func(http.Handler) http.Handler {
+	if realm == "" {
+		realm = s.AppName
+	}
+	if realm == "" {
+		realm = "Restricted"
+	}
+	challenge := `Basic realm=` + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || !s.checkBasic(user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				s.ErrorUnauthorized(w, r)
+				return
+			}
+			ctx := withPayload(r.Context(), &auth.Payload{Username: user})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// checkBasic reports whether user and pass are valid credentials.
+func (s *Socle) checkBasic(user, pass string) bool {
+	if s.basicUsers != nil {
+		return s.basicUsers.Check(user, pass)
+	}
+	if s.basicInsecure {
+		s.Logger.Error("basic auth: request refused, the default admin/admin credentials are disabled outside of debug mode")
+		return false
+	}
+	return auth.CheckPlain(user, pass, s.env.auth.basic.user, s.env.auth.basic.pass)
+}
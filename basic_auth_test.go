@@ -0,0 +1,83 @@
+package socle
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestInitBasicAuth(t *testing.T) {
+	dir := t.TempDir()
+	hash, err := bcrypt.GenerateFromPassword([]byte("lovelace"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users"), []byte("ada:"+string(hash)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		user, pass string
+		file       string
+		mode       string
+		basicEntry bool
+		ok         bool
+		// credentials sent to a route protected with BasicAuth, and the expected status
+		login  [2]string
+		status int
+	}{
+		{name: "default outside dev mode", user: "admin", pass: "admin", basicEntry: true, ok: false},
+		{name: "default in production mode", user: "admin", pass: "admin", mode: "production", basicEntry: true, ok: false},
+		{name: "default, no basic_auth entry", user: "admin", pass: "admin", ok: true, login: [2]string{"admin", "admin"}, status: http.StatusUnauthorized},
+		{name: "default in dev mode", user: "admin", pass: "admin", mode: "dev", basicEntry: true, ok: true, login: [2]string{"admin", "admin"}, status: http.StatusOK},
+		{name: "custom credentials", user: "admin", pass: "s3cret", basicEntry: true, ok: true, login: [2]string{"admin", "s3cret"}, status: http.StatusOK},
+		{name: "wrong password", user: "admin", pass: "s3cret", basicEntry: true, ok: true, login: [2]string{"admin", "admin"}, status: http.StatusUnauthorized},
+		{name: "users file", user: "admin", pass: "admin", file: "users", basicEntry: true, ok: true, login: [2]string{"ada", "lovelace"}, status: http.StatusOK},
+		{name: "users file ignores the single user", user: "admin", pass: "s3cret", file: "users", ok: true, login: [2]string{"admin", "s3cret"}, status: http.StatusUnauthorized},
+		{name: "missing users file", file: "missing", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Socle{
+				RootPath: dir,
+				Debug:    true, // the default of DEBUG, which does not accept the insecure defaults
+				AppName:  "app",
+				Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			e := &Entry{Name: "web"}
+			if tt.basicEntry {
+				e.Server.Middlewares = []string{"basic_auth"}
+			}
+			s.Entries = []*Entry{e}
+			s.env.mode = tt.mode
+			s.env.auth.basic.user, s.env.auth.basic.pass, s.env.auth.basic.file = tt.user, tt.pass, tt.file
+
+			err := s.initBasicAuth()
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok = %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+
+			h := s.BasicAuth("")(text("secret"))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetBasicAuth(tt.login[0], tt.login[1])
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if want := `Basic realm="app", charset="UTF-8"`; rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != want {
+				t.Errorf("WWW-Authenticate = %q, want %q", rec.Header().Get("WWW-Authenticate"), want)
+			}
+		})
+	}
+}
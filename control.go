@@ -0,0 +1,234 @@
+package socle
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/robfig/cron/v3"
+	"github.com/socle-framework/socle/pkg/control"
+	"github.com/socle-framework/socle/pkg/env"
+	"github.com/socle-framework/socle/pkg/logging"
+	"github.com/socle-framework/socle/pkg/maintenance"
+)
+
+// RPCServer is the control plane used by the socle CLI. Its commands are only served
+// to clients proving they know the EncryptionKey.
+type RPCServer struct {
+	s *Socle
+}
+
+// MaintenanceMode saves the maintenance state sent by `socle down` or `socle up`,
+// which is then picked up by every instance sharing the maintenance store.
+func (r *RPCServer) MaintenanceMode(st maintenance.State, resp *string) error {
+	err := r.s.Maintenance.Set(context.Background(), st)
+	if err != nil {
+		return err
+	}
+
+	if st.Enabled {
+		*resp = "Server in maintenance mode"
+	} else {
+		*resp = "Server live!"
+	}
+	return nil
+}
+
+// Status describes the running process.
+func (r *RPCServer) Status(_ control.Empty, resp *control.Status) error {
+	s := r.s
+	*resp = control.Status{
+		Version:     s.Version,
+		Debug:       s.debug.Load(),
+		Maintenance: s.Maintenance.Current().Enabled,
+		StartedAt:   s.startedAt,
+		Goroutines:  runtime.NumGoroutine(),
+	}
+	for _, e := range s.Entries {
+		resp.Entries = append(resp.Entries, e.Name)
+	}
+	if s.DB.Pool != nil {
+		resp.DBOpenConns = s.DB.Pool.Stats().OpenConnections
+	}
+	return nil
+}
+
+// ReloadLogging reads DEBUG and LOG_LEVEL from .env again and applies them. It is the
+// only reload of the control plane: the other settings of .env and socle.yaml build the
+// servers, connections and middlewares at startup and take a restart to change.
+func (r *RPCServer) ReloadLogging(_ control.Empty, resp *string) error {
+	s := r.s
+	if err := env.Reload(s.RootPath); err != nil {
+		return err
+	}
+
+	cfg := initEnvConfig()
+	level := logging.ParseLevel(cfg.log.level, slog.LevelInfo)
+	s.baseLevel.Set(level)
+	s.setDebug(cfg.debug)
+	*resp = fmt.Sprintf("Logging reloaded (debug: %v, level: %s)", cfg.debug, level)
+	return nil
+}
+
+// FlushCache empties the cache.
+func (r *RPCServer) FlushCache(_ control.Empty, resp *string) error {
+	if r.s.Cache == nil {
+		return errors.New("no cache configured")
+	}
+	if err := r.s.Cache.Empty(); err != nil {
+		return err
+	}
+	*resp = "Cache flushed"
+	return nil
+}
+
+// ScheduledJobs lists the jobs of the scheduler.
+func (r *RPCServer) ScheduledJobs(_ control.Empty, resp *[]control.ScheduledJob) error {
+	for _, entry := range r.s.Scheduler.Entries() {
+		name, _ := r.s.scheduled.Load(entry.ID)
+		job := control.ScheduledJob{
+			ID:   int(entry.ID),
+			Next: entry.Next,
+			Prev: entry.Prev,
+		}
+		job.Name, _ = name.(string)
+		*resp = append(*resp, job)
+	}
+	sort.Slice(*resp, func(i, j int) bool { return (*resp)[i].ID < (*resp)[j].ID })
+	return nil
+}
+
+// TriggerJob runs the scheduled job with the given id now, in the background.
+func (r *RPCServer) TriggerJob(id int, resp *string) error {
+	entry := r.s.Scheduler.Entry(cron.EntryID(id))
+	if !entry.Valid() {
+		return fmt.Errorf("no scheduled job with id %d", id)
+	}
+	go entry.Job.Run()
+	*resp = fmt.Sprintf("Job %d triggered", id)
+	return nil
+}
+
+// SetDebug turns debug logging on or off.
+func (r *RPCServer) SetDebug(on bool, resp *string) error {
+	r.s.setDebug(on)
+	*resp = fmt.Sprintf("Debug logging: %v", on)
+	return nil
+}
+
+// Drain stops accepting connections and shuts the process down gracefully, as SIGTERM does.
+func (r *RPCServer) Drain(_ control.Empty, resp *string) error {
+	r.s.Shutdown()
+	*resp = "Draining connections"
+	return nil
+}
+
+// Schedule adds fn to the scheduler under name, to run on the cron spec. Named jobs can be
+// listed and triggered with the socle CLI.
+func (s *Socle) Schedule(name, spec string, fn func()) (cron.EntryID, error) {
+	id, err := s.Scheduler.AddFunc(spec, s.observeCron(name, fn))
+	if err != nil {
+		return 0, err
+	}
+	s.scheduled.Store(id, name)
+	return id, nil
+}
+
+// Shutdown asks ListenAndServe to stop gracefully, as if the process received SIGTERM.
+func (s *Socle) Shutdown() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Socle) setDebug(on bool) {
+	s.debug.Store(on)
+	if on {
+		s.logLevel.Set(slog.LevelDebug)
+	} else {
+		s.logLevel.Set(s.baseLevel.Level())
+	}
+}
+
+// listenRPC starts the control plane on the unix socket RPC_SOCKET or, when only RPC_PORT
+// is set, on RPC_ADDRESS:RPC_PORT over TLS with RPC_TLS_CERT and RPC_TLS_KEY. It is not
+// started with the default KEY, which would let anyone in, nor on TCP without TLS.
+func (s *Socle) listenRPC() {
+	cfg := s.env.rpc
+	if cfg.socket == "" && cfg.port == "" {
+		return
+	}
+	if s.EncryptionKey == "" || s.EncryptionKey == defaultEncryptionKey {
+		s.Log.ErrorLog.Println("RPC server not started: set KEY in .env, the default key is refused")
+		return
+	}
+	if cfg.socket == "" && (cfg.tlsCert == "" || cfg.tlsKey == "") {
+		s.Log.ErrorLog.Println("RPC server not started: RPC_PORT requires RPC_TLS_CERT and RPC_TLS_KEY, or use RPC_SOCKET")
+		return
+	}
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("RPCServer", &RPCServer{s: s}); err != nil {
+		s.Log.ErrorLog.Println(err)
+		return
+	}
+
+	var listen net.Listener
+	var err error
+	if cfg.socket != "" {
+		socket := cfg.socket
+		if !filepath.IsAbs(socket) {
+			socket = filepath.Join(s.RootPath, socket)
+		}
+		_ = os.Remove(socket)
+		s.Log.InfoLog.Println("Starting RPC server on socket", socket)
+		listen, err = net.Listen("unix", socket)
+		if err == nil {
+			err = os.Chmod(socket, 0600)
+		}
+	} else {
+		s.Log.InfoLog.Println("Starting RPC server on port", cfg.port)
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(cfg.tlsCert, cfg.tlsKey)
+		if err == nil {
+			listen, err = tls.Listen("tcp", net.JoinHostPort(cfg.address, cfg.port), &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS13,
+			})
+		}
+	}
+	if err != nil {
+		s.Log.ErrorLog.Println(err)
+		return
+	}
+
+	s.rpcListener = listen
+	key := []byte(s.EncryptionKey)
+	go func() {
+		for {
+			rpcConn, err := listen.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			go func() {
+				if err := control.Authenticate(rpcConn, key); err != nil {
+					s.Log.ErrorLog.Println("RPC connection refused:", err)
+					rpcConn.Close()
+					return
+				}
+				srv.ServeConn(rpcConn)
+			}()
+		}
+	}()
+}
@@ -0,0 +1,40 @@
+package socle
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestListenRPC(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		socket  string
+		port    string
+		started bool
+	}{
+		{name: "socket", key: "0123456789abcdef0123456789abcdef", socket: "rpc.sock", started: true},
+		{name: "default key", key: defaultEncryptionKey, socket: "rpc.sock"},
+		{name: "empty key", socket: "rpc.sock"},
+		{name: "tcp without tls", key: "0123456789abcdef0123456789abcdef", port: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Socle{RootPath: t.TempDir(), EncryptionKey: tt.key}
+			s.Log.InfoLog = log.New(io.Discard, "", 0)
+			s.Log.ErrorLog = log.New(io.Discard, "", 0)
+			s.env.rpc.socket = tt.socket
+			s.env.rpc.address = "127.0.0.1"
+			s.env.rpc.port = tt.port
+
+			s.listenRPC()
+			if s.rpcListener != nil {
+				defer s.rpcListener.Close()
+			}
+			if started := s.rpcListener != nil; started != tt.started {
+				t.Errorf("started = %v, want %v", started, tt.started)
+			}
+		})
+	}
+}
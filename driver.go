@@ -3,9 +3,11 @@ package socle
 import (
 	"database/sql"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4"
 	_ "github.com/jackc/pgx/v4/stdlib"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // OpenDB opens a connection to a sql database. dbType must be one of postgres (or pgx).
@@ -15,7 +17,15 @@ func (c *Socle) OpenDB(dbType, dsn string) (*sql.DB, error) {
 		dbType = "pgx"
 	}
 
-	db, err := sql.Open(dbType, dsn)
+	var db *sql.DB
+	var err error
+	if c.tracerProvider != nil {
+		// spans for the queries run with a context
+		db, err = otelsql.Open(dbType, dsn, otelsql.WithTracerProvider(c.tracerProvider),
+			otelsql.WithAttributes(attribute.String("db.system", dbType)))
+	} else {
+		db, err = sql.Open(dbType, dsn)
+	}
 	if err != nil {
 		return nil, err
 	}
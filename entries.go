@@ -0,0 +1,171 @@
+package socle
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// supervisorEntry is the entry name that runs every enabled entry of socle.yaml
+// in the same process.
+const supervisorEntry = "supervisor"
+
+// httpEntries lists the entries served over HTTP by the chi router.
+var httpEntries = []string{"web", "api/rest", "api/graphql"}
+
+// apiTypes lists the types of api entries, in the order they are started.
+var apiTypes = []string{"rest", "graphql", "grpc"}
+
+// Entry is one runnable part of the application (web, api/rest, worker). All the
+// entries of a process share the DB, Cache, Session and Mail handles of Socle.
+type Entry struct {
+	Name   string
+	Server Server
+	Routes *chi.Mux
+
+	mounts       []func(r chi.Router) // routes of socle, behind the middlewares of the entry
+	publicMounts []func(r chi.Router) // routes of socle, outside of its authentication
+	handler      http.Handler
+}
+
+// Entry returns the entry named name run by this process, or nil when it is not running.
+func (s *Socle) Entry(name string) *Entry {
+	for _, e := range s.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// runs reports whether the entry named name is run by this process.
+func (s *Socle) runs(name string) bool {
+	return s.Entry(name) != nil
+}
+
+// entryNames returns the entries to run: the one given to New, or every enabled
+// entry of socle.yaml (except cli) in supervisor mode.
+func (s *Socle) entryNames() []string {
+	if s.entry != supervisorEntry {
+		return []string{s.entry}
+	}
+
+	var names []string
+	if s.appConfig.Entries.Web.Enabled {
+		names = append(names, "web")
+	}
+	if s.appConfig.Entries.Api.Enabled {
+		names = append(names, s.apiEntryNames()...)
+	}
+	if s.appConfig.Entries.Worker.Enabled {
+		names = append(names, "worker")
+	}
+	return names
+}
+
+// apiEntryNames returns the api entries declared in socle.yaml: every enabled type
+// when the api is multiple, its single type otherwise.
+func (s *Socle) apiEntryNames() []string {
+	api := s.appConfig.Entries.Api
+	if !api.Multiple {
+		if api.Type == "" {
+			return []string{"api/rest"}
+		}
+		return []string{"api/" + api.Type}
+	}
+
+	var names []string
+	for _, kind := range apiTypes {
+		if t, ok := api.Types[kind]; ok && t.Enabled {
+			names = append(names, "api/"+kind)
+		}
+	}
+	return names
+}
+
+// initEntries creates the entries of the process with their router and server settings.
+// Routes and Server point to the first HTTP entry, so that single entry applications
+// keep registering their routes on s.Routes.
+func (s *Socle) initEntries() error {
+	for _, name := range s.entryNames() {
+		e := &Entry{Name: name}
+		if InArrayStr(name, httpEntries) {
+			e.Server = s.newServer(name)
+			e.Routes = s.routes(e).(*chi.Mux)
+			if name == "api/graphql" {
+				e.mount(func(r chi.Router) {
+					r.Handle(s.env.graphQLPath, s.graphQLHandler())
+				})
+			}
+
+			if s.Routes == nil {
+				s.Routes = e.Routes
+				s.Server = e.Server
+			}
+		}
+		if name == "api/grpc" {
+			e.Server = s.newServer(name)
+			if err := s.initGRPC(e); err != nil {
+				return err
+			}
+		}
+		s.Entries = append(s.Entries, e)
+	}
+	return nil
+}
+
+func (s *Socle) newServer(name string) Server {
+	srv := Server{
+		Name:    s.env.serverName,
+		Address: s.env.serverAddress,
+	}
+
+	var security securityConfig
+	switch name {
+	case "api/rest", "api/graphql", "api/grpc":
+		switch name {
+		case "api/graphql":
+			srv.Port = s.apiPort("graphql", s.env.graphQLApiPort)
+		case "api/grpc":
+			srv.Port = s.apiPort("grpc", s.env.rpcApiPort)
+		default:
+			srv.Port = s.apiPort("rest", s.env.restApiPort)
+		}
+		srv.Middlewares = s.appConfig.Entries.Api.Middlewares
+		security = s.appConfig.Entries.Api.Security
+
+	default:
+		srv.Port = entryPort(s.appConfig.Entries.Web.Port, s.env.webPort)
+		srv.Middlewares = s.appConfig.Entries.Web.Middlewares
+		security = s.appConfig.Entries.Web.Security
+	}
+
+	srv.Secure = security.Enabled
+	srv.Security.Strategy = security.TLS.Strategy
+	srv.Security.MutualTLS = security.TLS.Mutual
+	srv.Security.CAName = security.TLS.CACertName
+	srv.Security.ServerCertName = security.TLS.ServerCertName
+	srv.Security.ClientCertName = security.TLS.ClientCertName
+	return srv
+}
+
+// apiPort returns the port declared in socle.yaml for the api type kind, or the one from .env.
+func (s *Socle) apiPort(kind, fallback string) string {
+	api := s.appConfig.Entries.Api
+	if t, ok := api.Types[kind]; ok && t.Port > 0 {
+		return strconv.Itoa(t.Port)
+	}
+	if !api.Multiple {
+		return entryPort(api.Port, fallback)
+	}
+	return fallback
+}
+
+// entryPort returns the port declared for the entry in socle.yaml, or the one from .env.
+func entryPort(port int, fallback string) string {
+	if port > 0 {
+		return strconv.Itoa(port)
+	}
+	return fallback
+}
@@ -0,0 +1,78 @@
+package socle
+
+import (
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestInitEntries(t *testing.T) {
+	multiple := entries{
+		Web: webEntry{Enabled: true, Port: 8000},
+		Api: apiEntry{Enabled: true, Multiple: true, Types: map[string]apiTypeConfig{
+			"rest":    {Enabled: true, Port: 8001},
+			"graphql": {Enabled: false},
+			"grpc":    {Enabled: true},
+		}},
+		Worker: defaultEntry{Enabled: true},
+		Cli:    defaultEntry{Enabled: true},
+	}
+
+	tests := []struct {
+		name    string
+		entry   string
+		config  entries
+		entries []string
+		ports   map[string]string
+	}{
+		{name: "single entry", entry: "web", config: multiple, entries: []string{"web"}, ports: map[string]string{"web": "8000"}},
+		{
+			name:    "supervisor",
+			entry:   supervisorEntry,
+			config:  multiple,
+			entries: []string{"web", "api/rest", "api/grpc", "worker"},
+			ports:   map[string]string{"web": "8000", "api/rest": "8001", "api/grpc": "8093"},
+		},
+		{
+			name:    "supervisor with a single api",
+			entry:   supervisorEntry,
+			config:  entries{Api: apiEntry{Enabled: true, Type: "graphql", Port: 9000}},
+			entries: []string{"api/graphql"},
+			ports:   map[string]string{"api/graphql": "9000"},
+		},
+		{name: "supervisor without entries", entry: supervisorEntry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Socle{entry: tt.entry}
+			s.Log.ErrorLog = log.New(io.Discard, "", 0)
+			s.appConfig.Entries = tt.config
+			s.env.webPort, s.env.restApiPort, s.env.graphQLApiPort, s.env.rpcApiPort = "8190", "8091", "8092", "8093"
+
+			if err := s.initEntries(); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range s.Entries {
+				names = append(names, e.Name)
+				if port, ok := tt.ports[e.Name]; ok && e.Server.Port != port {
+					t.Errorf("%s: port = %s, want %s", e.Name, e.Server.Port, port)
+				}
+				if InArrayStr(e.Name, httpEntries) && e.Routes == nil {
+					t.Errorf("%s: no routes", e.Name)
+				}
+			}
+			if !slices.Equal(names, tt.entries) {
+				t.Errorf("entries = %v, want %v", names, tt.entries)
+			}
+			// single entry applications keep registering their routes on s.Routes
+			if len(s.Entries) > 0 && s.Entries[0].Routes != nil && s.Routes != s.Entries[0].Routes {
+				t.Error("s.Routes is not the router of the first HTTP entry")
+			}
+			if s.runs("api/grpc") != (s.GRPC != nil) {
+				t.Errorf("gRPC server set = %v, want %v", s.GRPC != nil, s.runs("api/grpc"))
+			}
+		})
+	}
+}
@@ -9,48 +9,138 @@ import (
 
 	"github.com/socle-framework/socle/pkg/env"
 	"github.com/socle-framework/socle/pkg/ratelimiter"
+	"golang.org/x/crypto/acme"
 )
 
+// defaultEncryptionKey is the KEY used when .env sets none. It is public: the control
+// plane refuses it.
+const defaultEncryptionKey = "default-key-should-be-32-bytes!"
+
 type envConfig struct {
-	mode           string
-	debug          bool
-	apiPort        string
-	restApiPort    string
-	graphQLApiPort string
-	rpcApiPort     string
-	webPort        string
-	serverName     string
-	serverAddress  string
-	secure         bool
-	db             dbConfig
-	auth           authConfig
-	redis          redisConfig
-	cache          string
-	cookie         cookieConfig
-	sessionType    string
-	mail           mailConfig
-	migrationUrl   string
-	rateLimiter    ratelimiter.Config
-	uploads        uploadConfig
-	encryptionKey  string
-	storage        storageConfig
+	mode               string
+	debug              bool
+	apiPort            string
+	restApiPort        string
+	graphQLApiPort     string
+	rpcApiPort         string
+	graphQLPath        string
+	webPort            string
+	serverName         string
+	serverAddress      string
+	secure             bool
+	db                 dbConfig
+	auth               authConfig
+	redis              redisConfig
+	cache              string
+	cookie             cookieConfig
+	sessionType        string
+	mail               mailConfig
+	migrationUrl       string
+	rateLimiter        ratelimiter.Config
+	uploads            uploadConfig
+	encryptionKey      string
+	storage            storageConfig
+	shutdown           time.Duration
+	jobs               jobsConfig
+	maintenance        maintenanceConfig
+	i18n               i18nConfig
+	rpc                rpcConfig
+	acme               acmeConfig
+	certReloadInterval time.Duration
+	log                logConfig
+	metrics            metricsConfig
+	health             healthConfig
+}
+
+type healthConfig struct {
+	livenessPath  string
+	readinessPath string
+	timeout       time.Duration
+	minDiskFree   uint64
+	shutdownDelay time.Duration
+}
+
+type metricsConfig struct {
+	enabled bool
+	path    string
+	address string
+	port    string
+	entries []string
+}
+
+type logConfig struct {
+	format           string
+	level            string
+	sampleFirst      int
+	sampleThereafter int
+	sampleInterval   time.Duration
+}
+
+type acmeConfig struct {
+	email        string
+	domains      []string
+	directoryURL string
+	caRoots      string
+	cache        string
+	httpPort     string
+}
+
+type rpcConfig struct {
+	socket  string
+	address string
+	port    string
+	tlsCert string
+	tlsKey  string
+}
+
+type maintenanceConfig struct {
+	store   string
+	refresh time.Duration
+}
+
+type i18nConfig struct {
+	dir      string
+	fallback string
+}
+
+type jobsConfig struct {
+	store        string
+	concurrency  int
+	maxAttempts  int
+	visibility   time.Duration
+	pollInterval time.Duration
+	backoff      time.Duration
+	backoffMax   time.Duration
 }
 
 type authConfig struct {
-	basic basicConfig
-	token tokenConfig
+	basic       basicConfig
+	token       tokenConfig
+	remember    time.Duration
+	resetExpiry time.Duration
+	resetURL    string
 }
 
 type tokenConfig struct {
-	secret  string
-	exp     time.Duration
-	refresh time.Duration
-	iss     string
+	secret      string
+	exp         time.Duration
+	refresh     time.Duration
+	iss         string
+	aud         string
+	keys        []string
+	jwksURL     string
+	jwksRefresh time.Duration
+	jwksPath    string
+	store       string
+	refreshPath string
+	revokePath  string
 }
 
 type basicConfig struct {
-	user string
-	pass string
+	user  string
+	pass  string
+	file  string
+	realm string
 }
 
 type dbConfig struct {
@@ -83,9 +173,12 @@ type redisConfig struct {
 	enabled  bool
 }
 
+// Logger holds standard library loggers for the code written before Socle.Logger. They
+// write through it, at the info, error and debug levels.
 type Logger struct {
 	ErrorLog *log.Logger
 	InfoLog  *log.Logger
+	DebugLog *log.Logger
 }
 
 type uploadConfig struct {
@@ -167,18 +260,20 @@ func initEnvConfig() envConfig {
 		maxUploadSize = int64(max)
 	}
 	return envConfig{
-		mode:           env.GetString("MODE", "dev"),
+		mode:           env.GetString("MODE", "production"),
 		debug:          env.GetBool("DEBUG", true),
 		apiPort:        env.GetString("API_PORT", "8090"),
 		restApiPort:    env.GetString("REST_API_PORT", "8091"),
 		graphQLApiPort: env.GetString("GRAPHQL_API_PORT", "8092"),
 		rpcApiPort:     env.GetString("RPC_API_PORT", "8093"),
+		graphQLPath:    env.GetString("GRAPHQL_PATH", "/graphql"),
 		webPort:        env.GetString("WEB_PORT", "8190"),
 		serverName:     env.GetString("SERVER_NAME", "localhost"),
 		secure:         env.GetBool("SECURE", false),
 		cache:          env.GetString("CACHE", "memory"),
 		sessionType:    env.GetString("SESSION_TYPE", "cookie"),
 		migrationUrl:   env.GetString("MIGRATION_URL", "file://migrate/migration"),
+		shutdown:       time.Second * time.Duration(env.GetInt("SHUTDOWN_TIMEOUT", 30)),
 		db: dbConfig{
 			dbType:       env.GetString("DATABASE_TYPE", "postgres"),
 			host:         env.GetString("DATABASE_HOST", "localhost"),
@@ -226,24 +321,108 @@ func initEnvConfig() envConfig {
 		},
 
 		auth: authConfig{
+			remember:    time.Hour * 24 * time.Duration(env.GetInt("AUTH_REMEMBER_DAYS", 30)),
+			resetExpiry: time.Minute * time.Duration(env.GetInt("AUTH_RESET_EXPIRY", 60)),
+			// the reset links are never built from the Host header, which the client chooses
+			resetURL: env.GetString("AUTH_RESET_URL", "https://"+env.GetString("SERVER_NAME", "localhost")+"/users/reset-password"),
 			basic: basicConfig{
 				user: env.GetString("AUTH_BASIC_USER", "admin"),
 				pass: env.GetString("AUTH_BASIC_PASS", "admin"),
+				// user:hash lines, replacing AUTH_BASIC_USER and AUTH_BASIC_PASS
+				file:  env.GetString("AUTH_BASIC_FILE", ""),
+				realm: env.GetString("AUTH_BASIC_REALM", ""),
 			},
 			token: tokenConfig{
 				secret:  env.GetString("AUTH_TOKEN_SECRET", ""),
 				exp:     time.Hour * time.Duration(env.GetInt("AUTH_TOKEN_EXP", 24)),     // 1 day
 				refresh: time.Hour * time.Duration(env.GetInt("AUTH_TOKEN_REFRESH", 72)), // 3 days
 				iss:     env.GetString("AUTH_TOKEN_ISS", "app"),
+				// the audience defaults to the issuer, for the tokens an api issues to itself
+				aud: env.GetString("AUTH_TOKEN_AUD", env.GetString("AUTH_TOKEN_ISS", "app")),
+				// kid=path of the PEM key, the first one signing the tokens
+				keys:        splitList(env.GetString("AUTH_TOKEN_KEYS", "")),
+				jwksURL:     env.GetString("AUTH_JWKS_URL", ""),
+				jwksRefresh: time.Second * time.Duration(env.GetInt("AUTH_JWKS_REFRESH", 3600)),
+				jwksPath:    env.GetString("AUTH_JWKS_PATH", "/.well-known/jwks.json"),
+				// redis, badger or sql, chosen after the cache and the database when empty
+				store:       env.GetString("AUTH_TOKEN_STORE", ""),
+				refreshPath: env.GetString("AUTH_REFRESH_PATH", "/auth/refresh"),
+				revokePath:  env.GetString("AUTH_REVOKE_PATH", "/auth/revoke"),
 			},
 		},
 
+		log: logConfig{
+			format:           env.GetString("LOG_FORMAT", "text"),
+			level:            env.GetString("LOG_LEVEL", "info"),
+			sampleFirst:      env.GetInt("LOG_SAMPLE_FIRST", 0),
+			sampleThereafter: env.GetInt("LOG_SAMPLE_THEREAFTER", 100),
+			sampleInterval:   time.Second * time.Duration(env.GetInt("LOG_SAMPLE_INTERVAL", 1)),
+		},
+
+		metrics: metricsConfig{
+			enabled: env.GetBool("METRICS_ENABLED", false),
+			path:    env.GetString("METRICS_PATH", "/metrics"),
+			address: env.GetString("METRICS_ADDRESS", ""),
+			port:    env.GetString("METRICS_PORT", ""),
+			entries: splitList(env.GetString("METRICS_ENTRIES", "")),
+		},
+
+		health: healthConfig{
+			livenessPath:  env.GetString("HEALTH_LIVENESS_PATH", "/healthz"),
+			readinessPath: env.GetString("HEALTH_READINESS_PATH", "/readyz"),
+			timeout:       time.Millisecond * time.Duration(env.GetInt("HEALTH_TIMEOUT", 2000)),
+			minDiskFree:   uint64(env.GetInt("HEALTH_MIN_DISK_FREE", 100)) << 20, // MB
+			shutdownDelay: time.Second * time.Duration(env.GetInt("HEALTH_SHUTDOWN_DELAY", 0)),
+		},
+
+		jobs: jobsConfig{
+			store:        env.GetString("JOBS_STORE", ""),
+			concurrency:  env.GetInt("JOBS_CONCURRENCY", 5),
+			maxAttempts:  env.GetInt("JOBS_MAX_ATTEMPTS", 5),
+			visibility:   time.Second * time.Duration(env.GetInt("JOBS_VISIBILITY_TIMEOUT", 300)),
+			pollInterval: time.Millisecond * time.Duration(env.GetInt("JOBS_POLL_INTERVAL", 1000)),
+			backoff:      time.Second * time.Duration(env.GetInt("JOBS_BACKOFF", 10)),
+			backoffMax:   time.Second * time.Duration(env.GetInt("JOBS_BACKOFF_MAX", 3600)),
+		},
+
+		certReloadInterval: time.Second * time.Duration(env.GetInt("CERT_RELOAD_INTERVAL", 30)),
+
+		acme: acmeConfig{
+			email:        env.GetString("ACME_EMAIL", ""),
+			domains:      splitList(env.GetString("ACME_DOMAINS", "")),
+			directoryURL: env.GetString("ACME_DIRECTORY_URL", acme.LetsEncryptURL),
+			caRoots:      env.GetString("ACME_CA_ROOTS", ""),
+			cache:        env.GetString("ACME_CACHE", "fs"),
+			httpPort:     env.GetString("ACME_HTTP_PORT", "80"),
+		},
+
+		rpc: rpcConfig{
+			socket:  env.GetString("RPC_SOCKET", ""),
+			address: env.GetString("RPC_ADDRESS", "127.0.0.1"),
+			port:    env.GetString("RPC_PORT", ""),
+			tlsCert: env.GetString("RPC_TLS_CERT", ""),
+			tlsKey:  env.GetString("RPC_TLS_KEY", ""),
+		},
+
+		i18n: i18nConfig{
+			dir:      env.GetString("I18N_DIR", "lang"),
+			fallback: env.GetString("I18N_DEFAULT", "en"),
+		},
+
+		maintenance: maintenanceConfig{
+			store:   env.GetString("MAINTENANCE_STORE", ""),
+			refresh: time.Second * time.Duration(env.GetInt("MAINTENANCE_REFRESH", 5)),
+		},
+
 		rateLimiter: ratelimiter.Config{
 			RequestsPerTimeFrame: env.GetInt("RATE_LIMITER_REQUESTS_COUNT", 20),
 			TimeFrame:            time.Second * time.Duration(env.GetInt("RATE_LIMITER_TIME", 72)),
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", false),
+			Store:                env.GetString("RATE_LIMITER_STORE", ""),
+			Algorithm:            env.GetString("RATE_LIMITER_ALGORITHM", "fixed_window"),
+			MaxKeys:              env.GetInt("RATE_LIMITER_MAX_KEYS", 100000),
 		},
-		encryptionKey: env.GetString("KEY", "default-key-should-be-32-bytes!"),
+		encryptionKey: env.GetString("KEY", defaultEncryptionKey),
 		uploads: uploadConfig{
 			allowedMimeTypes: mimeTypes,
 			maxUploadSize:    maxUploadSize, // 10 MB par défaut
@@ -280,3 +459,14 @@ func initEnvConfig() envConfig {
 	}
 
 }
+
+// splitList splits a comma separated list, dropping the empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,12 +1,21 @@
 package socle
 
-import "github.com/socle-framework/socle/pkg/auth"
+import (
+	"context"
 
-func (s *Socle) GenerateApiToken(username string) (string, *auth.Payload, error) {
+	"github.com/socle-framework/socle/pkg/auth"
+)
+
+// GenerateApiToken returns an access token of username, with the roles, scopes, tenant
+// and custom claims of opts:
+//
+//	token, _, err := app.GenerateApiToken(user.Email, auth.WithRoles("admin"), auth.WithTenant(org.ID))
+func (s *Socle) GenerateApiToken(username string, opts ...auth.TokenOption) (string, *auth.Payload, error) {
 	tokenString, payload, err := s.Authenticator.GenerateToken(
 		username,
 		s.env.auth.token.exp,
 		s.env.auth.token.iss,
+		opts...,
 	)
 
 	if err != nil {
@@ -15,7 +24,16 @@ func (s *Socle) GenerateApiToken(username string) (string, *auth.Payload, error)
 	return tokenString, payload, nil
 }
 
+// GenerateRefreshToken returns a refresh token of username. With a token store, it starts
+// a new family like IssueRefreshToken. Without one, it is a long-lived token signed like
+// the access tokens, which cannot be rotated nor revoked.
+//
+// Deprecated: use IssueRefreshToken, which carries claims and the context of the request.
 func (s *Socle) GenerateRefreshToken(username string) (string, *auth.Payload, error) {
+	if _, ok := s.refresher(); ok {
+		return s.IssueRefreshToken(context.Background(), username)
+	}
+
 	tokenString, payload, err := s.Authenticator.GenerateToken(
 		username,
 		s.env.auth.token.refresh,
@@ -27,3 +45,28 @@ func (s *Socle) GenerateRefreshToken(username string) (string, *auth.Payload, er
 	}
 	return tokenString, payload, nil
 }
+
+// IssueRefreshToken returns a refresh token starting a new family, recorded in the token
+// store to be exchanged once on the refresh endpoint. The claims of opts are carried over
+// to the access tokens it is exchanged for. Without a token store, it returns
+// auth.ErrNoTokenStore.
+func (s *Socle) IssueRefreshToken(ctx context.Context, username string, opts ...auth.TokenOption) (string, *auth.Payload, error) {
+	ra, ok := s.refresher()
+	if !ok {
+		return "", nil, auth.ErrNoTokenStore
+	}
+
+	tokenString, payload, err := ra.GenerateRefreshToken(
+		ctx,
+		username,
+		s.env.auth.token.refresh,
+		s.env.auth.token.iss,
+		"",
+		opts...,
+	)
+
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, payload, nil
+}
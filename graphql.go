@@ -0,0 +1,58 @@
+package socle
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/handler"
+)
+
+// SetGraphQLSchema sets the schema served by the api/graphql entry on GRAPHQL_PATH.
+// Resolvers find the payload of the bearer token of the request, if any, with
+// auth.FromContext(p.Context), and the DB and Cache handles in the root object.
+func (s *Socle) SetGraphQLSchema(schema graphql.Schema) {
+	s.graphQL = handler.New(&handler.Config{
+		Schema:     &schema,
+		Pretty:     s.Debug,
+		Playground: s.Debug,
+		RootObjectFn: func(ctx context.Context, r *http.Request) map[string]interface{} {
+			return map[string]interface{}{
+				"db":    s.DB,
+				"cache": s.Cache,
+			}
+		},
+	})
+}
+
+func (s *Socle) graphQLHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.graphQL == nil {
+			s.ErrorStatus(w, http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx := r.Context()
+		payload, err := s.authenticate(r)
+		if err != nil {
+			s.ErrorUnauthorized(w, r)
+			return
+		}
+		if payload != nil {
+			ctx = withPayload(ctx, payload)
+		}
+
+		s.graphQL.ContextHandler(ctx, w, r)
+	})
+}
+
+// bearerToken returns the token of the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
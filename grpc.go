@@ -0,0 +1,299 @@
+package socle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/socle-framework/socle/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// grpcInterceptor is the gRPC equivalent of an http middleware.
+type grpcInterceptor struct {
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+// grpcInterceptorRegistry maps the names of the middleware registry to their gRPC
+// interceptors. Middlewares that only make sense over HTTP (session, no_surf...) have none.
+func (s *Socle) grpcInterceptorRegistry() map[string]grpcInterceptor {
+	return map[string]grpcInterceptor{
+		"request_id":             s.interceptor(s.grpcRequestID),
+		"recovery":               {s.grpcRecoveryUnary, s.grpcRecoveryStream},
+		"access_log":             {s.grpcAccessLogUnary, s.grpcAccessLogStream},
+		"tracing":                {s.grpcTracingUnary, s.grpcTracingStream},
+		"auth":                   s.interceptor(s.grpcAuth),
+		"rate_limit":             s.interceptor(s.grpcRateLimit),
+		"maintenance_mode_check": s.interceptor(s.grpcMaintenanceModeCheck),
+	}
+}
+
+// interceptor builds the unary and stream interceptors of fn, which checks a call and
+// returns the context to run it with.
+func (s *Socle) interceptor(fn func(ctx context.Context, method string) (context.Context, error)) grpcInterceptor {
+	return grpcInterceptor{
+		unary: func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := fn(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+		stream: func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := fn(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		},
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// initGRPC creates the gRPC server of the api/grpc entry, with the interceptors of its
+// middleware list, and the health and reflection services. Applications register their
+// services on s.GRPC before calling ListenAndServe.
+func (s *Socle) initGRPC(e *Entry) error {
+	registry := s.grpcInterceptorRegistry()
+	httpRegistry := s.middlewareRegistry()
+
+	logContext := s.interceptor(func(ctx context.Context, method string) (context.Context, error) {
+		return entryContext(ctx, e.Name), nil
+	})
+	unary := []grpc.UnaryServerInterceptor{logContext.unary}
+	stream := []grpc.StreamServerInterceptor{logContext.stream}
+	for _, name := range e.Server.Middlewares {
+		i, ok := registry[name]
+		if !ok {
+			if _, ok := httpRegistry[name]; !ok {
+				s.Log.ErrorLog.Printf("Middleware '%s' not found in registry\n", name)
+			}
+			continue
+		}
+		unary = append(unary, i.unary)
+		stream = append(stream, i.stream)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+
+	if e.Server.Secure {
+		cfg, err := s.tlsConfig(e.Server)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+
+	s.GRPC = grpc.NewServer(opts...)
+	s.grpcHealth = health.NewServer()
+	healthpb.RegisterHealthServer(s.GRPC, s.grpcHealth)
+	reflection.Register(s.GRPC)
+	return nil
+}
+
+func (s *Socle) serveGRPC(e *Entry) error {
+	s.Log.InfoLog.Printf("%s listening on  %s with security %v", e.Name, e.Server.getURL(), e.Server.Secure)
+	listen, err := net.Listen("tcp", e.Server.getURL())
+	if err != nil {
+		return err
+	}
+
+	err = s.GRPC.Serve(listen)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// stopGRPC marks the gRPC server as not serving and waits for the running calls until ctx
+// expires, then closes the remaining connections.
+func (s *Socle) stopGRPC(ctx context.Context) error {
+	s.grpcHealth.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GRPC.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.GRPC.Stop()
+		return errors.New("grpc: running calls did not finish before shutdown timeout")
+	}
+}
+
+// grpcRequestID stores the x-request-id metadata of the call, or a new id, in the context
+// under the same key as middleware.RequestID, and sends it back in the response headers.
+func (s *Socle) grpcRequestID(ctx context.Context, method string) (context.Context, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-request-id"); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+	logging.AddAttrs(ctx, slog.String("request_id", id))
+	return context.WithValue(ctx, middleware.RequestIDKey, id), nil
+}
+
+func (s *Socle) grpcAccessLogUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *Socle) grpcAccessLogStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall logs a served call, like the access_log middleware does for HTTP.
+func (s *Socle) logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+
+	s.Logger.LogAttrs(ctx, level, "call",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("remote", remote),
+	)
+}
+
+func (s *Socle) grpcRecoveryUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.Log.ErrorLog.Printf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (s *Socle) grpcRecoveryStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.Log.ErrorLog.Printf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// grpcPublicMethod reports whether method is served without authentication.
+func grpcPublicMethod(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+// grpcAuth validates the bearer token of the authorization metadata with the
+// Authenticator and stores its payload in the context.
+func (s *Socle) grpcAuth(ctx context.Context, method string) (context.Context, error) {
+	if grpcPublicMethod(method) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" || s.Authenticator == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+
+	payload, err := s.Authenticator.ValidateToken(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+	return withPayload(ctx, payload), nil
+}
+
+// grpcRateLimit limits the calls per client address with the RateLimiter, when one is set.
+func (s *Socle) grpcRateLimit(ctx context.Context, method string) (context.Context, error) {
+	if s.RateLimiter == nil || grpcPublicMethod(method) {
+		return ctx, nil
+	}
+
+	key := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			key = host
+		}
+	}
+
+	if res := s.RateLimiter.Allow(key); !res.Allowed {
+		s.rateLimited(entryFromContext(ctx))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds())))))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return ctx, nil
+}
+
+func (s *Socle) grpcMaintenanceModeCheck(ctx context.Context, method string) (context.Context, error) {
+	st := s.Maintenance.Current()
+	if !st.Enabled || grpcPublicMethod(method) {
+		return ctx, nil
+	}
+	if p, ok := peer.FromContext(ctx); ok && st.Allows(clientIP(p.Addr.String())) {
+		return ctx, nil
+	}
+
+	message := st.Message
+	if message == "" {
+		message = "server in maintenance mode"
+	}
+	retryAfter := int(math.Ceil(st.RetryAfter(time.Now()).Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+	return nil, status.Error(codes.Unavailable, message)
+}
@@ -0,0 +1,165 @@
+package socle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/maintenance"
+	"github.com/socle-framework/socle/pkg/ratelimiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// callInfo is what the handler of a test call saw in its context.
+type callInfo struct {
+	user, requestID, entry string
+}
+
+// testService is a gRPC service of two methods, Check answering like the health service
+// and Panic panicking, registered without generated code.
+func testService(calls chan<- callInfo) *grpc.ServiceDesc {
+	method := func(name string, fn func(ctx context.Context) (any, error)) grpc.MethodDesc {
+		return grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(healthpb.HealthCheckRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) { return fn(ctx) }
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Service/" + name}
+				return interceptor(ctx, in, info, handler)
+			},
+		}
+	}
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			method("Check", func(ctx context.Context) (any, error) {
+				id, _ := ctx.Value(middleware.RequestIDKey).(string)
+				calls <- callInfo{user: auth.Username(ctx), requestID: id, entry: entryFromContext(ctx)}
+				return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+			}),
+			method("Panic", func(ctx context.Context) (any, error) {
+				panic("boom")
+			}),
+		},
+	}
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	var errorLog bytes.Buffer
+	authenticator := auth.NewJWTAuthenticator("0123456789abcdef0123456789abcdef", "api", "socle")
+	m, err := maintenance.NewManager(context.Background(), maintenance.NewFileStore(filepath.Join(t.TempDir(), "maintenance.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ErrorLog = log.New(io.Discard, "", 0)
+	limiter, _ := ratelimiter.New("", 3, time.Minute)
+
+	s := &Socle{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Authenticator: authenticator,
+		Maintenance:   m,
+		RateLimiter:   limiter,
+	}
+	s.Log.InfoLog = log.New(io.Discard, "", 0)
+	s.Log.ErrorLog = log.New(&errorLog, "", 0)
+	e := &Entry{Name: "api/grpc", Server: Server{Middlewares: []string{
+		"request_id", "recovery", "maintenance_mode_check", "auth", "rate_limit",
+		// HTTP only, skipped silently
+		"session",
+		"missing",
+	}}}
+	if err := s.initGRPC(e); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errorLog.String(), "'missing' not found") {
+		t.Errorf("error log = %q, want the unknown middleware reported", errorLog.String())
+	}
+
+	calls := make(chan callInfo, 1)
+	s.GRPC.RegisterService(testService(calls), struct{}{})
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = s.GRPC.Serve(ln) }()
+	t.Cleanup(s.GRPC.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	token, _, err := authenticator.GenerateToken("ada", time.Minute, "socle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the calls are made in order, the rate limiter counting the authenticated ones
+	tests := []struct {
+		name        string
+		method      string
+		token       string
+		maintenance bool
+		code        codes.Code
+	}{
+		{name: "health without token", method: "/grpc.health.v1.Health/Check", code: codes.OK},
+		{name: "without token", method: "/test.Service/Check", code: codes.Unauthenticated},
+		{name: "bad token", method: "/test.Service/Check", token: "not-a-token", code: codes.Unauthenticated},
+		{name: "token", method: "/test.Service/Check", token: token, code: codes.OK},
+		{name: "panic", method: "/test.Service/Panic", token: token, code: codes.Internal},
+		{name: "maintenance", method: "/test.Service/Check", token: token, maintenance: true, code: codes.Unavailable},
+		{name: "health in maintenance", method: "/grpc.health.v1.Health/Check", maintenance: true, code: codes.OK},
+		{name: "last call of the limit", method: "/test.Service/Check", token: token, code: codes.OK},
+		{name: "rate limited", method: "/test.Service/Check", token: token, code: codes.ResourceExhausted},
+		{name: "health when rate limited", method: "/grpc.health.v1.Health/Check", code: codes.OK},
+	}
+	for _, tt := range tests {
+		if err := m.Set(context.Background(), maintenance.State{Enabled: tt.maintenance}); err != nil {
+			t.Fatal(err)
+		}
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+		if tt.token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+		}
+
+		var header metadata.MD
+		err := conn.Invoke(ctx, tt.method, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, grpc.Header(&header))
+		if code := status.Code(err); code != tt.code {
+			t.Fatalf("%s: code = %v (%v), want %v", tt.name, code, err, tt.code)
+		}
+		if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-1" {
+			t.Errorf("%s: x-request-id header = %v, want req-1", tt.name, got)
+		}
+		if tt.code == codes.ResourceExhausted && len(header.Get("retry-after")) != 1 {
+			t.Errorf("%s: no retry-after header", tt.name)
+		}
+		if tt.code == codes.OK && strings.HasPrefix(tt.method, "/test.") {
+			got := <-calls
+			if want := (callInfo{user: "ada", requestID: "req-1", entry: "api/grpc"}); got != want {
+				t.Errorf("%s: handler context = %+v, want %+v", tt.name, got, want)
+			}
+		}
+	}
+	if !strings.Contains(errorLog.String(), "panic in /test.Service/Panic: boom") {
+		t.Errorf("error log = %q, want the panic", errorLog.String())
+	}
+}
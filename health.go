@@ -0,0 +1,197 @@
+package socle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HealthCheck reports whether a dependency of the application is usable. It must return
+// before ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+type healthCheck struct {
+	name string
+	fn   HealthCheck
+}
+
+type healthChecks struct {
+	mu     sync.Mutex
+	checks []healthCheck
+}
+
+// CheckResult is the outcome of a health check, as shown by the readiness endpoint.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthReport is the body of the health endpoints.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// RegisterHealthCheck adds fn to the checks run by the readiness endpoint. A check
+// registered under the name of an existing one replaces it.
+func (s *Socle) RegisterHealthCheck(name string, fn HealthCheck) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	for i, c := range s.health.checks {
+		if c.name == name {
+			s.health.checks[i].fn = fn
+			return
+		}
+	}
+	s.health.checks = append(s.health.checks, healthCheck{name, fn})
+}
+
+// initHealth registers the checks of the database, redis, badger, SMTP server and disk
+// space, for those in use.
+func (s *Socle) initHealth() error {
+	if s.DB.Pool != nil {
+		s.RegisterHealthCheck("database", func(ctx context.Context) error {
+			return s.DB.Pool.PingContext(ctx)
+		})
+	}
+
+	if redisPool != nil {
+		s.RegisterHealthCheck("redis", func(ctx context.Context) error {
+			conn, err := redisPool.GetContext(ctx)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			_, err = conn.Do("PING")
+			return err
+		})
+	}
+
+	if badgerConn != nil {
+		s.RegisterHealthCheck("badger", func(ctx context.Context) error {
+			if badgerConn.IsClosed() {
+				return errors.New("database is closed")
+			}
+			return nil
+		})
+	}
+
+	if s.env.mail.smtp.host != "" {
+		addr := net.JoinHostPort(s.env.mail.smtp.host, strconv.Itoa(s.env.mail.smtp.port))
+		s.RegisterHealthCheck("smtp", func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		})
+	}
+
+	if s.env.health.minDiskFree > 0 {
+		dir := filepath.Join(s.RootPath, "tmp")
+		min := s.env.health.minDiskFree
+		s.RegisterHealthCheck("disk", func(ctx context.Context) error {
+			free, err := diskFree(dir)
+			if errors.Is(err, errors.ErrUnsupported) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if free < min {
+				return fmt.Errorf("%d MB free under %s, below %d MB", free>>20, dir, min>>20)
+			}
+			return nil
+		})
+	}
+
+	return nil
+}
+
+// Ready runs the health checks concurrently and reports whether the application can
+// serve requests. It is not ready during the maintenance mode and the graceful shutdown.
+func (s *Socle) Ready(ctx context.Context) (HealthReport, bool) {
+	report := HealthReport{Status: "ok"}
+
+	switch {
+	case s.shuttingDown.Load():
+		report.Status, report.Reason = "fail", "shutting down"
+	case s.Maintenance != nil && s.Maintenance.Current().Enabled:
+		report.Status, report.Reason = "fail", "maintenance"
+	}
+
+	s.health.mu.Lock()
+	checks := append([]healthCheck(nil), s.health.checks...)
+	s.health.mu.Unlock()
+
+	if len(checks) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, s.env.health.timeout)
+		defer cancel()
+
+		results := make([]CheckResult, len(checks))
+		var wg sync.WaitGroup
+		for i, c := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				err := c.fn(ctx)
+				results[i] = CheckResult{Status: "ok", Duration: time.Since(start).String()}
+				if err != nil {
+					results[i].Status = "fail"
+					results[i].Error = err.Error()
+				}
+			}()
+		}
+		wg.Wait()
+
+		report.Checks = make(map[string]CheckResult, len(checks))
+		for i, c := range checks {
+			report.Checks[c.name] = results[i]
+			if results[i].Status != "ok" {
+				report.Status = "fail"
+			}
+		}
+	}
+
+	return report, report.Status == "ok"
+}
+
+// HealthCheckMiddleware answers the liveness (HEALTH_LIVENESS_PATH, /healthz) and
+// readiness (HEALTH_READINESS_PATH, /readyz) probes, before the next middlewares: list it
+// before maintenance_mode_check and auth.
+func (s *Socle) HealthCheckMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.URL.Path {
+		case s.env.health.livenessPath:
+			_ = s.WriteJSON(w, http.StatusOK, HealthReport{Status: "ok"})
+
+		case s.env.health.readinessPath:
+			report, ok := s.Ready(r.Context())
+			status := http.StatusOK
+			if !ok {
+				status = http.StatusServiceUnavailable
+			}
+			_ = s.WriteJSON(w, status, report)
+
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package socle
+
+import "errors"
+
+// diskFree is not implemented on this platform; the disk check always passes.
+func diskFree(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
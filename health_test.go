@@ -0,0 +1,240 @@
+package socle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/socle-framework/socle/pkg/maintenance"
+)
+
+func newHealthSocle(t *testing.T) *Socle {
+	t.Helper()
+	s := &Socle{RootPath: t.TempDir()}
+	// the disk check measures the tmp folder of the application
+	if err := os.Mkdir(filepath.Join(s.RootPath, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s.env.health.livenessPath = "/healthz"
+	s.env.health.readinessPath = "/readyz"
+	s.env.health.timeout = 50 * time.Millisecond
+	return s
+}
+
+func TestRegisterHealthCheck(t *testing.T) {
+	s := newHealthSocle(t)
+	s.RegisterHealthCheck("cache", func(ctx context.Context) error { return errors.New("down") })
+	s.RegisterHealthCheck("queue", func(ctx context.Context) error { return nil })
+	s.RegisterHealthCheck("cache", func(ctx context.Context) error { return nil })
+
+	report, ok := s.Ready(context.Background())
+	if !ok || len(report.Checks) != 2 {
+		t.Errorf("Ready = %+v, %v, want the replaced check passing", report, ok)
+	}
+}
+
+func TestReady(t *testing.T) {
+	pass := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name         string
+		checks       map[string]HealthCheck
+		shuttingDown bool
+		maintenance  bool
+		ok           bool
+		reason       string
+		failed       []string
+	}{
+		{name: "no checks", ok: true},
+		{name: "passing", checks: map[string]HealthCheck{"database": pass, "redis": pass}, ok: true},
+		{name: "failing", checks: map[string]HealthCheck{"database": pass, "redis": fail}, failed: []string{"redis"}},
+		{name: "timeout", checks: map[string]HealthCheck{"smtp": slow}, failed: []string{"smtp"}},
+		{name: "shutting down", checks: map[string]HealthCheck{"database": pass}, shuttingDown: true, reason: "shutting down"},
+		{name: "maintenance", maintenance: true, reason: "maintenance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHealthSocle(t)
+			for name, fn := range tt.checks {
+				s.RegisterHealthCheck(name, fn)
+			}
+			s.shuttingDown.Store(tt.shuttingDown)
+			if tt.maintenance {
+				m, err := maintenance.NewManager(context.Background(), maintenance.NewFileStore(filepath.Join(s.RootPath, "maintenance.json")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				m.ErrorLog = log.New(io.Discard, "", 0)
+				if err := m.Set(context.Background(), maintenance.State{Enabled: true}); err != nil {
+					t.Fatal(err)
+				}
+				s.Maintenance = m
+			}
+
+			start := time.Now()
+			report, ok := s.Ready(context.Background())
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Ready took %v, want the checks bounded by the timeout", elapsed)
+			}
+			if ok != tt.ok || report.Reason != tt.reason {
+				t.Fatalf("Ready = %+v, %v, want ok = %v, reason %q", report, ok, tt.ok, tt.reason)
+			}
+			if want := map[bool]string{true: "ok", false: "fail"}[tt.ok]; report.Status != want {
+				t.Errorf("Status = %q, want %q", report.Status, want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("Checks = %v, want %d results", report.Checks, len(tt.checks))
+			}
+			failed := map[string]bool{}
+			for _, name := range tt.failed {
+				failed[name] = true
+			}
+			for name, res := range report.Checks {
+				if (res.Status == "fail") != failed[name] || failed[name] && res.Error == "" {
+					t.Errorf("%s: %+v, want failed = %v", name, res, failed[name])
+				}
+				if res.Duration == "" {
+					t.Errorf("%s: no duration", name)
+				}
+			}
+		})
+	}
+}
+
+func TestInitHealth(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	smtpPort, _ := strconv.Atoi(port)
+
+	// a port nobody listens on
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	tests := []struct {
+		name        string
+		smtpPort    int
+		minDiskFree uint64
+		checks      []string
+		failed      []string
+	}{
+		{name: "nothing in use"},
+		{name: "smtp up", smtpPort: smtpPort, checks: []string{"smtp"}},
+		{name: "smtp down", smtpPort: closedPort, checks: []string{"smtp"}, failed: []string{"smtp"}},
+		{name: "disk", minDiskFree: 1, checks: []string{"disk"}},
+		{name: "disk full", minDiskFree: 1 << 62, checks: []string{"disk"}, failed: []string{"disk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHealthSocle(t)
+			s.env.health.timeout = time.Second
+			if tt.smtpPort != 0 {
+				s.env.mail.smtp.host = host
+				s.env.mail.smtp.port = tt.smtpPort
+			}
+			s.env.health.minDiskFree = tt.minDiskFree
+			if err := s.initHealth(); err != nil {
+				t.Fatal(err)
+			}
+
+			report, ok := s.Ready(context.Background())
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("Checks = %v, want %v", report.Checks, tt.checks)
+			}
+			for _, name := range tt.checks {
+				if _, found := report.Checks[name]; !found {
+					t.Errorf("check %s not registered", name)
+				}
+			}
+			for _, name := range tt.failed {
+				if res := report.Checks[name]; res.Status != "fail" {
+					t.Errorf("%s: %+v, want failed", name, res)
+				}
+			}
+			if ok != (len(tt.failed) == 0) {
+				t.Errorf("ok = %v with failed checks %v", ok, tt.failed)
+			}
+		})
+	}
+}
+
+func TestHealthCheckMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		failing bool
+		status  int
+		report  string
+	}{
+		{name: "liveness", method: http.MethodGet, path: "/healthz", status: http.StatusOK, report: "ok"},
+		{name: "liveness with a failing check", method: http.MethodGet, path: "/healthz", failing: true, status: http.StatusOK, report: "ok"},
+		{name: "readiness", method: http.MethodGet, path: "/readyz", status: http.StatusOK, report: "ok"},
+		{name: "readiness head", method: http.MethodHead, path: "/readyz", status: http.StatusOK},
+		{name: "not ready", method: http.MethodGet, path: "/readyz", failing: true, status: http.StatusServiceUnavailable, report: "fail"},
+		{name: "post", method: http.MethodPost, path: "/readyz", status: http.StatusTeapot},
+		{name: "other path", method: http.MethodGet, path: "/users", status: http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHealthSocle(t)
+			s.RegisterHealthCheck("database", func(ctx context.Context) error {
+				if tt.failing {
+					return errors.New("connection refused")
+				}
+				return nil
+			})
+
+			rr := httptest.NewRecorder()
+			s.HealthCheckMiddleware(next).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if tt.report == "" {
+				return
+			}
+			var report HealthReport
+			if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.report {
+				t.Errorf("report = %+v, want status %q", report, tt.report)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package socle
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	"github.com/socle-framework/socle/pkg/i18n"
+)
+
+// socleLang holds the messages of socle, in the lang/<locale> catalogs.
+//
+//go:embed lang
+var socleLang embed.FS
+
+// sessionLocaleKey is the session key of the locale chosen with SetLocale.
+const sessionLocaleKey = "locale"
+
+// initI18n loads the catalogs of the I18N_DIR directory of the application, lang by
+// default. The messages of socle are added to the locales of the application and to the
+// I18N_DEFAULT one, the application overriding them.
+func (s *Socle) initI18n() error {
+	defaults := i18n.NewBundle(s.env.i18n.fallback)
+	if err := defaults.LoadFS(socleLang, "lang"); err != nil {
+		return err
+	}
+
+	app := i18n.NewBundle(s.env.i18n.fallback)
+	dir := s.env.i18n.dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.RootPath, dir)
+	}
+	if err := app.LoadDir(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	s.I18n = i18n.NewBundle(s.env.i18n.fallback)
+	for _, locale := range app.Locales() {
+		s.I18n.AddMessages(locale, defaults.Messages(locale))
+		s.I18n.AddMessages(locale, app.Messages(locale))
+	}
+	return nil
+}
+
+// I18nMiddleware negotiates the locale of the request: the one chosen with SetLocale when
+// the session middleware comes first, else the best of Accept-Language, else I18N_DEFAULT.
+// The handlers translate to it with T, and the templ components with i18n.T.
+func (s *Socle) I18nMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var preferred []string
+		if s.sessionLoaded(r.Context(), "i18n") {
+			preferred = append(preferred, s.Session.GetString(r.Context(), sessionLocaleKey))
+		}
+		preferred = append(preferred, r.Header.Get("Accept-Language"))
+
+		locale := s.I18n.Match(preferred...)
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		ctx := i18n.NewContext(r.Context(), s.I18n.Localizer(locale))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sessionLoaded reports whether the session middleware runs before the middleware name on
+// the entry serving the request of ctx, the session being unusable otherwise.
+func (s *Socle) sessionLoaded(ctx context.Context, name string) bool {
+	e := s.Entry(entryFromContext(ctx))
+	if e == nil {
+		return false
+	}
+	session := slices.Index(e.Server.Middlewares, "session")
+	return session >= 0 && session < slices.Index(e.Server.Middlewares, name)
+}
+
+// SetLocale stores in the session the locale of the user, the supported one best matching
+// locale, and returns it. The i18n middleware prefers it to Accept-Language.
+func (s *Socle) SetLocale(ctx context.Context, locale string) string {
+	locale = s.I18n.Match(locale)
+	s.Session.Put(ctx, sessionLocaleKey, locale)
+	return locale
+}
+
+// Localizer returns the localizer of the locale of ctx, or of I18N_DEFAULT.
+func (s *Socle) Localizer(ctx context.Context) *i18n.Localizer {
+	if l, ok := i18n.FromContext(ctx); ok {
+		return l
+	}
+	return s.I18n.Localizer(s.I18n.Fallback())
+}
+
+// T translates key to the locale of ctx, with its parameters given by name and value pairs:
+//
+//	app.T(r.Context(), "welcome", "name", user.FirstName)
+func (s *Socle) T(ctx context.Context, key string, args ...any) string {
+	return s.Localizer(ctx).T(key, args...)
+}
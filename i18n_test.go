@@ -0,0 +1,56 @@
+package socle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestI18nMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lang"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lang", "fr.yaml"), []byte("welcome: \"Bonjour\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Socle{RootPath: dir}
+	s.env.i18n.fallback = "en"
+	s.env.i18n.dir = "lang"
+	if err := s.initI18n(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := s.I18nMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(s.T(r.Context(), "welcome") + "|" + s.T(r.Context(), "validation.required")))
+	}))
+
+	tests := []struct {
+		acceptLanguage string
+		locale         string
+		body           string
+	}{
+		// the messages of socle complete those of the application
+		{"fr-FR,fr;q=0.9", "fr", "Bonjour|Ce champ est obligatoire"},
+		{"de", "en", "welcome|This field cannot be blank"},
+		{"", "en", "welcome|This field cannot be blank"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Language"); got != tt.locale {
+			t.Errorf("%q: Content-Language = %q, want %q", tt.acceptLanguage, got, tt.locale)
+		}
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("%q: Vary = %q", tt.acceptLanguage, rec.Header().Get("Vary"))
+		}
+		if rec.Body.String() != tt.body {
+			t.Errorf("%q: body = %q, want %q", tt.acceptLanguage, rec.Body.String(), tt.body)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package sqldialect smooths the differences between the databases of the SQL stores of
+// socle: postgres, mysql or mariadb, and sqlite.
+package sqldialect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Postgres reports whether dbType, the DATABASE_TYPE of the app, is postgres.
+func Postgres(dbType string) bool {
+	return dbType == "postgres" || dbType == "postgresql" || dbType == "pgx"
+}
+
+// MySQL reports whether dbType is mysql or mariadb.
+func MySQL(dbType string) bool {
+	return dbType == "mysql" || dbType == "mariadb"
+}
+
+// SQLite reports whether dbType is sqlite.
+func SQLite(dbType string) bool {
+	return dbType == "sqlite" || dbType == "sqlite3"
+}
+
+// Upsert returns the clause following an INSERT so that, when the row conflicts with an
+// existing one on key, its columns are updated with the inserted values instead of
+// failing. Without columns, the existing row is kept as is.
+func Upsert(dbType, key string, columns ...string) string {
+	if MySQL(dbType) {
+		if len(columns) == 0 {
+			return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", key, key)
+		}
+		set := make([]string, len(columns))
+		for i, c := range columns {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", c, c)
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+
+	// postgres and sqlite
+	if len(columns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", key)
+	}
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		set[i] = fmt.Sprintf("%s = excluded.%s", c, c)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(set, ", "))
+}
+
+// Rebind replaces the ? placeholders of query by $n ones for postgres.
+func Rebind(dbType, query string) string {
+	if !Postgres(dbType) {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
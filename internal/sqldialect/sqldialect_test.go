@@ -0,0 +1,42 @@
+package sqldialect
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE socle_jobs SET run_at = ?, attempts = ? WHERE id = ?"
+	tests := []struct {
+		dbType string
+		want   string
+	}{
+		{"mysql", query},
+		{"mariadb", query},
+		{"sqlite", query},
+		{"postgres", "UPDATE socle_jobs SET run_at = $1, attempts = $2 WHERE id = $3"},
+		{"postgresql", "UPDATE socle_jobs SET run_at = $1, attempts = $2 WHERE id = $3"},
+		{"pgx", "UPDATE socle_jobs SET run_at = $1, attempts = $2 WHERE id = $3"},
+	}
+	for _, tt := range tests {
+		if got := Rebind(tt.dbType, query); got != tt.want {
+			t.Errorf("Rebind(%s) = %q, want %q", tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		dbType  string
+		columns []string
+		want    string
+	}{
+		{"postgres", []string{"state", "updated_at"}, "ON CONFLICT (id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at"},
+		{"sqlite", []string{"state"}, "ON CONFLICT (id) DO UPDATE SET state = excluded.state"},
+		{"mysql", []string{"state", "updated_at"}, "ON DUPLICATE KEY UPDATE state = VALUES(state), updated_at = VALUES(updated_at)"},
+		{"postgres", nil, "ON CONFLICT (id) DO NOTHING"},
+		{"mariadb", nil, "ON DUPLICATE KEY UPDATE id = id"},
+	}
+	for _, tt := range tests {
+		if got := Upsert(tt.dbType, "id", tt.columns...); got != tt.want {
+			t.Errorf("Upsert(%s, %v) = %q, want %q", tt.dbType, tt.columns, got, tt.want)
+		}
+	}
+}
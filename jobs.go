@@ -0,0 +1,82 @@
+package socle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/socle-framework/socle/pkg/jobs"
+)
+
+// initJobs creates the job queue on the store chosen by JOBS_STORE. When JOBS_STORE is
+// empty, the queue uses redis or badger if a cache is configured, then the database.
+func (s *Socle) initJobs() error {
+	store := s.env.jobs.store
+	if store == "" {
+		switch {
+		case redisPool != nil:
+			store = "redis"
+		case badgerConn != nil:
+			store = "badger"
+		case s.DB.Pool != nil:
+			store = "sql"
+		}
+	}
+
+	var js jobs.Store
+	switch store {
+	case "redis":
+		if redisPool == nil {
+			redisPool = s.createRedisPool()
+		}
+		js = jobs.NewRedisStore(redisPool, s.redisPrefix())
+	case "badger":
+		if badgerConn == nil {
+			badgerConn = s.createBadgerConn()
+		}
+		if badgerConn == nil {
+			return errors.New("jobs: unable to open badger database")
+		}
+		js = jobs.NewBadgerStore(badgerConn)
+	case "sql", "postgres", "mysql", "mariadb":
+		if s.DB.Pool == nil {
+			return errors.New("jobs: the sql store requires a database connection")
+		}
+		js = jobs.NewSQLStore(s.DB.Pool, s.DB.DBType)
+	case "":
+		if s.runs("worker") {
+			return errors.New("jobs: the worker entry requires a job store, set JOBS_STORE")
+		}
+		return nil
+	default:
+		return errors.New("jobs: unknown store " + store)
+	}
+
+	s.Jobs = jobs.New(js, s.env.jobs.maxAttempts)
+	s.Jobs.InfoLog = s.Log.InfoLog
+	s.Jobs.ErrorLog = s.Log.ErrorLog
+	return nil
+}
+
+// startWorker runs the job workers when the worker entry is run by the process.
+// The returned channel is closed once the workers have stopped after ctx is done.
+func (s *Socle) startWorker(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if !s.runs("worker") {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		s.Jobs.Work(ctx, jobs.WorkerConfig{
+			Concurrency:  s.env.jobs.concurrency,
+			PollInterval: s.env.jobs.pollInterval,
+			Visibility:   s.env.jobs.visibility,
+			BackoffBase:  s.env.jobs.backoff,
+			BackoffMax:   s.env.jobs.backoffMax,
+			// leaves time to release the interrupted jobs before the shutdown timeout
+			Drain: s.env.shutdown * 9 / 10,
+		})
+	}()
+	return done
+}
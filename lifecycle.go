@@ -0,0 +1,157 @@
+package socle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/socle-framework/mailer"
+)
+
+// Hook is a function run by Socle at a given point of its lifecycle.
+type Hook func(ctx context.Context) error
+
+// OnStart registers a hook run before the server starts accepting connections.
+// Start hooks run in registration order, and the first error aborts the start.
+func (s *Socle) OnStart(h Hook) {
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+	s.hooks.start = append(s.hooks.start, h)
+}
+
+// OnShutdown registers a hook run once in-flight requests are drained and before
+// Socle closes its own resources (database, redis and badger). Shutdown hooks run
+// in reverse registration order, like deferred calls, so resources opened first are
+// released last.
+func (s *Socle) OnShutdown(h Hook) {
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+	s.hooks.shutdown = append(s.hooks.shutdown, h)
+}
+
+func (s *Socle) runStartHooks(ctx context.Context) error {
+	s.hooks.Lock()
+	hooks := append([]Hook(nil), s.hooks.start...)
+	s.hooks.Unlock()
+
+	for _, h := range hooks {
+		if err := h(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Socle) runShutdownHooks(ctx context.Context) error {
+	s.hooks.Lock()
+	hooks := append([]Hook(nil), s.hooks.shutdown...)
+	s.hooks.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releaseResources stops the background workers started by Socle and closes the
+// shared connections. It waits for running cron jobs and queued mails until ctx expires.
+func (s *Socle) releaseResources(ctx context.Context) error {
+	var errs []error
+
+	if s.rpcListener != nil {
+		_ = s.rpcListener.Close()
+	}
+
+	if s.Scheduler != nil {
+		select {
+		case <-s.Scheduler.Stop().Done():
+		case <-ctx.Done():
+			errs = append(errs, errors.New("scheduler: running jobs did not finish before shutdown timeout"))
+		}
+	}
+
+	if err := s.drainMail(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := s.runShutdownHooks(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := s.shutdownTracing(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.DB.Pool != nil {
+		if err := s.DB.Pool.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if redisPool != nil {
+		if err := redisPool.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if badgerConn != nil {
+		if err := badgerConn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// listenForMail sends the messages pushed on Mail.Jobs, in place of Mail.ListenForMail
+// which cannot be stopped. The outcome of every message is reported on Mail.Results
+// when it has room, failures being logged as well. Once mailStop is closed, the messages
+// already queued are sent before mailDone is closed.
+func (s *Socle) listenForMail() {
+	defer close(s.mailDone)
+
+	for {
+		select {
+		case msg := <-s.Mail.Jobs:
+			s.sendQueuedMail(msg)
+		case <-s.mailStop:
+			for {
+				select {
+				case msg := <-s.Mail.Jobs:
+					s.sendQueuedMail(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Socle) sendQueuedMail(msg mailer.Message) {
+	err := s.sendMail(context.Background(), msg)
+	if err != nil {
+		s.Log.ErrorLog.Printf("mailer: sending %q to %s: %v", msg.Subject, msg.To, err)
+	}
+	select {
+	case s.Mail.Results <- mailer.Result{Success: err == nil, Error: err}:
+	default:
+	}
+}
+
+// drainMail stops the mail listener and waits for it to send the messages already
+// queued on Mail.Jobs, including the one being sent.
+func (s *Socle) drainMail(ctx context.Context) error {
+	if s.mailStop == nil {
+		return nil
+	}
+	s.mailStopOnce.Do(func() { close(s.mailStop) })
+
+	select {
+	case <-s.mailDone:
+		return nil
+	case <-ctx.Done():
+		return errors.New("mailer: queued messages were not sent before shutdown timeout")
+	}
+}
@@ -0,0 +1,60 @@
+package socle
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/socle-framework/mailer"
+)
+
+func TestDrainMail(t *testing.T) {
+	s := &Socle{RootPath: t.TempDir()}
+	s.Log.ErrorLog = log.New(io.Discard, "", 0)
+	if err := s.initMailer(); err != nil {
+		t.Fatal(err)
+	}
+	// no templates: the sends fail right away, which is enough to follow them
+	s.Mail.Templates = filepath.Join(s.RootPath, "missing")
+	s.mailStop = make(chan struct{})
+	s.mailDone = make(chan struct{})
+
+	// queued before the listener starts, and so still queued when the drain begins
+	const queued = 5
+	for i := 0; i < queued; i++ {
+		s.Mail.Jobs <- mailer.Message{To: "user@example.com", Subject: "welcome", Template: "welcome"}
+	}
+	go s.listenForMail()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.drainMail(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Mail.Jobs); n != 0 {
+		t.Errorf("%d messages left on Mail.Jobs", n)
+	}
+	if n := len(s.Mail.Results); n != queued {
+		t.Errorf("%d results, want %d", n, queued)
+	}
+	select {
+	case <-s.mailDone:
+	default:
+		t.Error("mail listener still running")
+	}
+
+	// drained twice on a failed start
+	if err := s.drainMail(ctx); err != nil {
+		t.Errorf("second drain: %v", err)
+	}
+}
+
+func TestDrainMailWithoutListener(t *testing.T) {
+	s := &Socle{}
+	if err := s.drainMail(context.Background()); err != nil {
+		t.Errorf("drainMail = %v, want nil without a listener", err)
+	}
+}
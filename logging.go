@@ -0,0 +1,108 @@
+package socle
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/logging"
+)
+
+// initLoggers creates s.Logger from LOG_FORMAT, LOG_LEVEL and the LOG_SAMPLE_* settings,
+// and the legacy s.Log loggers writing through it.
+func (s *Socle) initLoggers() error {
+	s.logLevel = new(slog.LevelVar)
+	s.baseLevel.Set(logging.ParseLevel(s.env.log.level, slog.LevelInfo))
+
+	opts := logging.Options{
+		Format:    s.env.log.format,
+		Level:     s.logLevel,
+		AddSource: s.Debug,
+	}
+	if s.env.log.sampleFirst > 0 {
+		opts.Sampling = &logging.Sampling{
+			First:      s.env.log.sampleFirst,
+			Thereafter: s.env.log.sampleThereafter,
+			Interval:   s.env.log.sampleInterval,
+		}
+	}
+
+	s.Logger = logging.New(os.Stdout, opts).With("process", s.entry)
+	s.Log.InfoLog = slog.NewLogLogger(s.Logger.Handler(), slog.LevelInfo)
+	s.Log.ErrorLog = slog.NewLogLogger(s.Logger.Handler(), slog.LevelError)
+	s.Log.DebugLog = slog.NewLogLogger(s.Logger.Handler(), slog.LevelDebug)
+	s.setDebug(s.Debug)
+	return nil
+}
+
+type entryKey struct{}
+
+// entryContext returns a copy of ctx for a request served by entry, with the log fields
+// of the request: every record logged with it carries the entry name, and the request id
+// and user once known.
+func entryContext(ctx context.Context, entry string) context.Context {
+	ctx = context.WithValue(ctx, entryKey{}, entry)
+	return logging.NewContext(ctx, slog.String("entry", entry))
+}
+
+// entryFromContext returns the name of the entry serving the request of ctx.
+func entryFromContext(ctx context.Context) string {
+	entry, _ := ctx.Value(entryKey{}).(string)
+	return entry
+}
+
+// logContext sets the entry context of the requests served by entry.
+func (s *Socle) logContext(entry string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(entryContext(r.Context(), entry)))
+		})
+	}
+}
+
+// RequestIDMiddleware is chi's middleware.RequestID, also adding the id to the log fields.
+func (s *Socle) RequestIDMiddleware(next http.Handler) http.Handler {
+	return middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.AddAttrs(r.Context(), slog.String("request_id", middleware.GetReqID(r.Context())))
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// AccessLogMiddleware logs every request once served, with its status, size and duration.
+func (s *Socle) AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		s.Logger.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
+
+// withPayload stores the payload of an authenticated request in ctx, and adds its user
+// to the log fields.
+func withPayload(ctx context.Context, payload *auth.Payload) context.Context {
+	logging.AddAttrs(ctx, slog.String("user", payload.Username))
+	return auth.NewContext(ctx, payload)
+}
@@ -0,0 +1,51 @@
+package socle
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/logging"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Socle{Logger: logging.New(&buf, logging.Options{Format: "json"})}
+
+	h := s.logContext("api/rest")(s.RequestIDMiddleware(s.AccessLogMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the user authenticated by an inner middleware is logged by the access log
+			withPayload(r.Context(), &auth.Payload{Username: "ada"})
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("created"))
+		}),
+	)))
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":        "request",
+		"level":      "INFO",
+		"method":     "POST",
+		"path":       "/users",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(len("created")),
+		"entry":      "api/rest",
+		"request_id": "req-1",
+		"user":       "ada",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package socle
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/socle-framework/mailer"
+)
+
+// sessionUserKey is the session key of the logged in user id.
+const sessionUserKey = "userID"
+
+// Login logs userID in the session of ctx. The session token is renewed first, so that a
+// token planted in the browser before the login (session fixation) is worthless after.
+func (s *Socle) Login(ctx context.Context, userID int) error {
+	if err := s.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+	s.Session.Put(ctx, sessionUserKey, userID)
+	return nil
+}
+
+// Logout destroys the session of ctx, logging its user out.
+func (s *Socle) Logout(ctx context.Context) error {
+	return s.Session.Destroy(ctx)
+}
+
+// SessionUserID returns the id of the user logged in the session of ctx.
+func (s *Socle) SessionUserID(ctx context.Context) (int, bool) {
+	id, ok := s.Session.Get(ctx, sessionUserKey).(int)
+	return id, ok
+}
+
+// IsAuthenticated reports whether a user is logged in the session of r.
+func (s *Socle) IsAuthenticated(r *http.Request) bool {
+	_, ok := s.SessionUserID(r.Context())
+	return ok
+}
+
+// rememberCookie returns the name of the remember-me cookie.
+func (s *Socle) rememberCookie() string {
+	return s.env.cookie.name + "_remember"
+}
+
+// SetRememberCookie sets the remember-me cookie of userID, holding token for
+// AUTH_REMEMBER_DAYS. Store only the hash of token, see auth.NewSecretToken.
+func (s *Socle) SetRememberCookie(w http.ResponseWriter, userID int, token string) {
+	secure, _ := strconv.ParseBool(s.env.cookie.secure)
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.rememberCookie(),
+		Value:    strconv.Itoa(userID) + "|" + token,
+		Path:     "/",
+		Domain:   s.env.cookie.domain,
+		Expires:  time.Now().Add(s.env.auth.remember),
+		MaxAge:   int(s.env.auth.remember.Seconds()),
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// RememberCookie returns the user id and the token of the remember-me cookie of r.
+func (s *Socle) RememberCookie(r *http.Request) (int, string, bool) {
+	c, err := r.Cookie(s.rememberCookie())
+	if err != nil {
+		return 0, "", false
+	}
+	id, token, found := strings.Cut(c.Value, "|")
+	if !found || token == "" {
+		return 0, "", false
+	}
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, "", false
+	}
+	return userID, token, true
+}
+
+// ForgetRememberCookie deletes the remember-me cookie.
+func (s *Socle) ForgetRememberCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.rememberCookie(),
+		Value:    "",
+		Path:     "/",
+		Domain:   s.env.cookie.domain,
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// RememberLifetime returns how long the remember-me cookies are valid, after
+// AUTH_REMEMBER_DAYS.
+func (s *Socle) RememberLifetime() time.Duration {
+	return s.env.auth.remember
+}
+
+// PasswordResetExpiry returns how long the password reset links are valid, after
+// AUTH_RESET_EXPIRY.
+func (s *Socle) PasswordResetExpiry() time.Duration {
+	return s.env.auth.resetExpiry
+}
+
+// SendPasswordReset mails to to the password reset link of token, at AUTH_RESET_URL, with
+// the password-reset templates of the mail directory, in the locale of ctx. The templates
+// translate with the T function of their data: {{call .T "mail.password_reset.intro"}}.
+func (s *Socle) SendPasswordReset(ctx context.Context, to, token string) error {
+	link := s.env.auth.resetURL + "?token=" + url.QueryEscape(token)
+	l := s.Localizer(ctx)
+	return s.SendMail(ctx, mailer.Message{
+		To:       to,
+		Subject:  l.T("mail.password_reset.subject"),
+		Template: "password-reset",
+		Data: map[string]any{
+			"Link":    link,
+			"Expires": s.env.auth.resetExpiry,
+			"Locale":  l.Locale,
+			"T":       l.T,
+		},
+	})
+}
@@ -0,0 +1,121 @@
+package socle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+func TestLogin(t *testing.T) {
+	s := &Socle{Session: scs.New()}
+	s.Session.Cookie.Name = "session"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/visit", func(w http.ResponseWriter, r *http.Request) {
+		s.Session.Put(r.Context(), "visited", true)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Login(r.Context(), 42); err != nil {
+			t.Error(err)
+		}
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Logout(r.Context()); err != nil {
+			t.Error(err)
+		}
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := s.SessionUserID(r.Context()); !ok || id != 42 || !s.IsAuthenticated(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	h := s.Session.LoadAndSave(mux)
+
+	// do serves path with the session cookie, and returns the new one if set
+	do := func(path string, cookie *http.Cookie) (*http.Cookie, int) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "session" {
+				return c, rr.Code
+			}
+		}
+		return cookie, rr.Code
+	}
+
+	anonymous, _ := do("/visit", nil)
+	if _, code := do("/me", anonymous); code != http.StatusUnauthorized {
+		t.Errorf("anonymous session authenticated")
+	}
+	logged, _ := do("/login", anonymous)
+	if logged.Value == anonymous.Value {
+		t.Error("the session token was not renewed by the login")
+	}
+	if _, code := do("/me", logged); code != http.StatusOK {
+		t.Errorf("status after login = %d", code)
+	}
+	if _, code := do("/me", anonymous); code != http.StatusUnauthorized {
+		t.Error("the session token of before the login is authenticated")
+	}
+	do("/logout", logged)
+	if _, code := do("/me", logged); code != http.StatusUnauthorized {
+		t.Error("the session is authenticated after the logout")
+	}
+}
+
+func TestRememberCookie(t *testing.T) {
+	s := &Socle{}
+	s.env.cookie.name = "app"
+	s.env.cookie.secure = "true"
+	s.env.auth.remember = 30 * 24 * time.Hour
+
+	rr := httptest.NewRecorder()
+	s.SetRememberCookie(rr, 42, "TOKEN")
+	set := rr.Result().Cookies()
+	if len(set) != 1 {
+		t.Fatalf("cookies = %v", set)
+	}
+	c := set[0]
+	if c.Name != "app_remember" || !c.HttpOnly || !c.Secure || c.MaxAge != int(s.env.auth.remember.Seconds()) {
+		t.Errorf("cookie = %+v", c)
+	}
+
+	tests := []struct {
+		name   string
+		value  string
+		userID int
+		token  string
+		ok     bool
+	}{
+		{name: "set", value: c.Value, userID: 42, token: "TOKEN", ok: true},
+		{name: "no token", value: "42|"},
+		{name: "no separator", value: "42"},
+		{name: "bad id", value: "x|TOKEN"},
+		{name: "no cookie"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.value != "" {
+				req.AddCookie(&http.Cookie{Name: "app_remember", Value: tt.value})
+			}
+			userID, token, ok := s.RememberCookie(req)
+			if userID != tt.userID || token != tt.token || ok != tt.ok {
+				t.Errorf("RememberCookie = %d, %q, %v, want %d, %q, %v", userID, token, ok, tt.userID, tt.token, tt.ok)
+			}
+		})
+	}
+
+	rr = httptest.NewRecorder()
+	s.ForgetRememberCookie(rr)
+	if set := rr.Result().Cookies(); len(set) != 1 || set[0].Name != "app_remember" || set[0].MaxAge >= 0 {
+		t.Errorf("ForgetRememberCookie cookies = %+v", set)
+	}
+}
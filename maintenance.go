@@ -0,0 +1,94 @@
+package socle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/socle-framework/socle/pkg/maintenance"
+)
+
+// maintenanceCookie is the cookie letting a browser bypass the maintenance mode.
+const maintenanceCookie = "socle_maintenance"
+
+// initMaintenance loads the maintenance state from the store chosen by MAINTENANCE_STORE.
+// When MAINTENANCE_STORE is empty, the state is shared through redis or badger if a cache
+// is configured, or kept in tmp/maintenance.json otherwise.
+func (s *Socle) initMaintenance() error {
+	kind := s.env.maintenance.store
+	if kind == "" {
+		switch {
+		case redisPool != nil:
+			kind = "redis"
+		case badgerConn != nil:
+			kind = "badger"
+		default:
+			kind = "file"
+		}
+	}
+
+	var store maintenance.Store
+	switch kind {
+	case "redis":
+		if redisPool == nil {
+			redisPool = s.createRedisPool()
+		}
+		store = maintenance.NewRedisStore(redisPool, s.redisPrefix())
+	case "badger":
+		if badgerConn == nil {
+			badgerConn = s.createBadgerConn()
+		}
+		if badgerConn == nil {
+			return errors.New("maintenance: unable to open badger database")
+		}
+		store = maintenance.NewBadgerStore(badgerConn)
+	case "sql", "postgres", "mysql", "mariadb":
+		if s.DB.Pool == nil {
+			return errors.New("maintenance: the sql store requires a database connection")
+		}
+		store = maintenance.NewSQLStore(s.DB.Pool, s.DB.DBType)
+	case "file":
+		store = maintenance.NewFileStore(s.RootPath + "/tmp/maintenance.json")
+	default:
+		return errors.New("maintenance: unknown store " + kind)
+	}
+
+	m, err := maintenance.NewManager(context.Background(), store)
+	if err != nil {
+		return err
+	}
+	m.ErrorLog = s.Log.ErrorLog
+	s.Maintenance = m
+	return nil
+}
+
+// inMaintenance reports whether the request r must be refused because of the maintenance
+// mode, taking the allowed addresses and the bypass cookie into account. The address is
+// r.RemoteAddr, which the real_ip middleware replaces with the X-Real-IP, True-Client-IP
+// or X-Forwarded-For header of the request: use real_ip only behind a proxy setting these
+// headers itself, else any client can claim an allowed address.
+func (s *Socle) inMaintenance(r *http.Request, st maintenance.State) bool {
+	if !st.Enabled {
+		return false
+	}
+
+	if st.Allows(clientIP(r.RemoteAddr)) {
+		return false
+	}
+
+	if st.Secret != "" {
+		if c, err := r.Cookie(maintenanceCookie); err == nil && c.Value == st.BypassToken() {
+			return false
+		}
+	}
+	return true
+}
+
+// clientIP returns the host part of addr, which may have no port once middleware.RealIP ran.
+func clientIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,224 @@
+package socle
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the collectors socle updates itself. The others read their value when
+// the metrics are scraped.
+type metrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	cronRuns     *prometheus.CounterVec
+	cronDuration *prometheus.HistogramVec
+	rateLimited  *prometheus.CounterVec
+}
+
+// initMetrics creates the Metrics registry when METRICS_ENABLED is set, with the
+// collectors of the database, redis, badger, mailer, scheduler and rate limiter.
+// Applications register their own collectors on s.Metrics. The metrics are served by the
+// admin server of METRICS_PORT, or on the HTTP entries listed in METRICS_ENTRIES (web,
+// api/rest...), behind their middlewares: they are never exposed on an entry by default.
+func (s *Socle) initMetrics() error {
+	if !s.env.metrics.enabled {
+		return nil
+	}
+	if s.env.metrics.port == "" && len(s.env.metrics.entries) == 0 {
+		return errors.New("metrics: set METRICS_PORT to serve them on an admin server, or METRICS_ENTRIES to serve them on HTTP entries")
+	}
+
+	reg := prometheus.NewRegistry()
+	sizes := prometheus.ExponentialBuckets(100, 10, 6)
+
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "socle_http_requests_total",
+			Help: "HTTP requests served, by entry, method, route and status.",
+		}, []string{"entry", "method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "socle_http_request_duration_seconds",
+			Help:    "Time spent serving HTTP requests, by entry, method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"entry", "method", "route"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "socle_http_request_size_bytes",
+			Help:    "Size of the HTTP request bodies, by entry, method and route.",
+			Buckets: sizes,
+		}, []string{"entry", "method", "route"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "socle_http_response_size_bytes",
+			Help:    "Size of the HTTP response bodies, by entry, method and route.",
+			Buckets: sizes,
+		}, []string{"entry", "method", "route"}),
+		cronRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "socle_cron_runs_total",
+			Help: "Runs of the scheduled jobs, by job and result.",
+		}, []string{"job", "result"}),
+		cronDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "socle_cron_run_duration_seconds",
+			Help:    "Time spent running the scheduled jobs, by job.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"job"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "socle_rate_limit_rejections_total",
+			Help: "Requests rejected by the rate limiter, by entry.",
+		}, []string{"entry"}),
+	}
+
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.requestSize, m.responseSize,
+		m.cronRuns, m.cronDuration, m.rateLimited,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "socle_mail_queue_length",
+			Help: "Mails waiting to be sent.",
+		}, func() float64 {
+			return float64(len(s.Mail.Jobs))
+		}),
+	)
+
+	if s.DB.Pool != nil {
+		reg.MustRegister(collectors.NewDBStatsCollector(s.DB.Pool, s.env.db.name))
+	}
+
+	if redisPool != nil {
+		reg.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "socle_redis_pool_active_connections",
+				Help: "Connections of the redis pool, idle or in use.",
+			}, func() float64 {
+				return float64(redisPool.Stats().ActiveCount)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "socle_redis_pool_idle_connections",
+				Help: "Idle connections of the redis pool.",
+			}, func() float64 {
+				return float64(redisPool.Stats().IdleCount)
+			}),
+		)
+	}
+
+	if badgerConn != nil {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "socle_badger_size_bytes",
+			Help: "Size of the badger database, LSM tree and value log.",
+		}, func() float64 {
+			lsm, vlog := badgerConn.Size()
+			return float64(lsm + vlog)
+		}))
+	}
+
+	s.Metrics = reg
+	s.metrics = m
+
+	// the entries of the list run by other processes serve their own metrics
+	for _, name := range s.env.metrics.entries {
+		if e := s.Entry(name); e != nil && e.Routes != nil {
+			e.mount(func(r chi.Router) {
+				r.Handle(s.env.metrics.path, s.metricsHandler())
+			})
+		}
+	}
+	return nil
+}
+
+// metricsHandler serves the metrics of s.Metrics in the Prometheus or OpenMetrics format.
+func (s *Socle) metricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.Metrics, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorLog:          s.Log.ErrorLog,
+	})
+}
+
+// metricsServer returns the admin server exposing the metrics on METRICS_PORT, when set.
+func (s *Socle) metricsServer() *http.Server {
+	if s.Metrics == nil || s.env.metrics.port == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(s.env.metrics.path, s.metricsHandler())
+	return &http.Server{
+		Addr:              s.env.metrics.address + ":" + s.env.metrics.port,
+		ErrorLog:          s.Log.ErrorLog,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// MetricsMiddleware records the count, latency and sizes of the requests, by chi route
+// pattern so that the path parameters don't create a series per value.
+func (s *Socle) MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.metrics
+		if m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		entry := entryFromContext(r.Context())
+
+		m.requests.WithLabelValues(entry, r.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(entry, r.Method, route).Observe(time.Since(start).Seconds())
+		if r.ContentLength > 0 {
+			m.requestSize.WithLabelValues(entry, r.Method, route).Observe(float64(r.ContentLength))
+		}
+		m.responseSize.WithLabelValues(entry, r.Method, route).Observe(float64(ww.BytesWritten()))
+	})
+}
+
+// observeCron wraps fn, the scheduled job name, to record its runs and duration.
+func (s *Socle) observeCron(name string, fn func()) func() {
+	return func() {
+		m := s.metrics
+		if m == nil {
+			fn()
+			return
+		}
+
+		start := time.Now()
+		result := "success"
+		defer func() {
+			if r := recover(); r != nil {
+				result = "panic"
+				m.cronRuns.WithLabelValues(name, result).Inc()
+				panic(r)
+			}
+			m.cronDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+			m.cronRuns.WithLabelValues(name, result).Inc()
+		}()
+		fn()
+	}
+}
+
+// rateLimited counts a request of entry rejected by the rate limiter.
+func (s *Socle) rateLimited(entry string) {
+	if s.metrics != nil {
+		s.metrics.rateLimited.WithLabelValues(entry).Inc()
+	}
+}
@@ -0,0 +1,132 @@
+package socle
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newMetricsSocle returns a socle running the web and api/rest entries, the latter with
+// the metrics middleware.
+func newMetricsSocle(t *testing.T, port string, entries ...string) *Socle {
+	t.Helper()
+	s := &Socle{}
+	s.Log.ErrorLog = log.New(io.Discard, "", 0)
+	s.env.metrics.enabled = true
+	s.env.metrics.path = "/metrics"
+	s.env.metrics.port = port
+	s.env.metrics.entries = entries
+
+	web := &Entry{Name: "web"}
+	api := &Entry{Name: "api/rest", Server: Server{Middlewares: []string{"metrics"}}}
+	for _, e := range []*Entry{web, api} {
+		e.Routes = s.routes(e).(*chi.Mux)
+	}
+	s.Entries = []*Entry{web, api}
+	return s
+}
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestInitMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		port    string
+		entries []string
+		ok      bool
+		server  bool
+		served  map[string]bool // the entries serving the metrics
+	}{
+		{name: "nowhere to serve them"},
+		{name: "admin server", port: "9090", ok: true, server: true, served: map[string]bool{}},
+		{name: "listed entry", entries: []string{"api/rest"}, ok: true, served: map[string]bool{"api/rest": true}},
+		{name: "entry run by another process", entries: []string{"api/grpc", "web"}, ok: true, served: map[string]bool{"web": true}},
+		{name: "both", port: "9090", entries: []string{"web"}, ok: true, server: true, served: map[string]bool{"web": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMetricsSocle(t, tt.port, tt.entries...)
+			err := s.initMetrics()
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok = %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			s.mountRoutes()
+
+			for _, e := range s.Entries {
+				rec := get(e.handler, "/metrics")
+				if served := rec.Code == http.StatusOK; served != tt.served[e.Name] {
+					t.Errorf("%s: GET /metrics = %d, want served = %v", e.Name, rec.Code, tt.served[e.Name])
+				}
+			}
+			srv := s.metricsServer()
+			if (srv != nil) != tt.server {
+				t.Fatalf("admin server = %v, want %v", srv != nil, tt.server)
+			}
+			if srv != nil {
+				if srv.Addr != ":9090" {
+					t.Errorf("admin server address = %q", srv.Addr)
+				}
+				if rec := get(srv.Handler, "/metrics"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "go_goroutines") {
+					t.Errorf("admin server: GET /metrics = %d", rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestInitMetricsDisabled(t *testing.T) {
+	s := newMetricsSocle(t, "")
+	s.env.metrics.enabled = false
+	if err := s.initMetrics(); err != nil || s.Metrics != nil || s.metricsServer() != nil {
+		t.Errorf("initMetrics = %v with Metrics %v, want nothing set up", err, s.Metrics)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	s := newMetricsSocle(t, "9090")
+	if err := s.initMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	api := s.Entry("api/rest")
+	api.Routes.Get("/users/{id}", text("user"))
+	s.mountRoutes()
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		get(api.handler, path)
+	}
+	s.rateLimited("api/rest")
+	s.observeCron("prune", func() {})()
+	func() {
+		defer func() { _ = recover() }()
+		s.observeCron("prune", func() { panic("boom") })()
+	}()
+
+	body := get(s.metricsServer().Handler, "/metrics").Body.String()
+	for _, want := range []string{
+		// by route pattern, not one series per user
+		`socle_http_requests_total{entry="api/rest",method="GET",route="/users/{id}",status="200"} 2`,
+		`socle_http_requests_total{entry="api/rest",method="GET",route="unmatched",status="404"} 1`,
+		`socle_http_request_duration_seconds_count{entry="api/rest",method="GET",route="/users/{id}"} 2`,
+		`socle_http_response_size_bytes_sum{entry="api/rest",method="GET",route="/users/{id}"} 8`,
+		`socle_rate_limit_rejections_total{entry="api/rest"} 1`,
+		`socle_cron_runs_total{job="prune",result="success"} 1`,
+		`socle_cron_runs_total{job="prune",result="panic"} 1`,
+		`socle_mail_queue_length 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package socle
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -13,15 +16,21 @@ import (
 
 func (s *Socle) middlewareRegistry() map[string]func(http.Handler) http.Handler {
 	return map[string]func(http.Handler) http.Handler{
-		"request_id":             middleware.RequestID,
+		"request_id":             s.RequestIDMiddleware,
+		"access_log":             s.AccessLogMiddleware,
+		"metrics":                s.MetricsMiddleware,
+		"tracing":                s.TracingMiddleware,
 		"real_ip":                middleware.RealIP,
 		"recovery":               middleware.Recoverer,
 		"session":                s.SessionLoadMiddleware,
 		"no_surf":                s.NoSurfMiddleware, // CSRF protection
 		"maintenance_mode_check": s.MaintenanceModeCheckMiddleware,
-
-		//"auth":       s.AuthMiddleware,
-		//"healthcheck": s.HealthCheckMiddleware,
+		"healthcheck":            s.HealthCheckMiddleware,
+		"auth":                   s.AuthMiddleware,
+		"auth_optional":          s.OptionalAuthMiddleware,
+		"basic_auth":             s.BasicAuthMiddleware,
+		"rate_limit":             s.RateLimitMiddleware,
+		"i18n":                   s.I18nMiddleware,
 	}
 }
 
@@ -32,18 +41,18 @@ func (s *Socle) applyMiddlewares(r chi.Router, names []string) {
 		if mw, ok := registry[name]; ok {
 			r.Use(mw)
 		} else {
-			fmt.Printf("Middleware '%s' not found in registry\n", name)
+			s.Logger.Warn("middleware not found in registry", "middleware", name)
 		}
 	}
 }
 
 func (s *Socle) SessionLoadMiddleware(next http.Handler) http.Handler {
-	s.Log.InfoLog.Println("SessionLoad callled")
+	s.Log.DebugLog.Println("SessionLoad called")
 	return s.Session.LoadAndSave(next)
 }
 
 func (s *Socle) NoSurfMiddleware(next http.Handler) http.Handler {
-	s.Log.InfoLog.Println("No surf middleware callled")
+	s.Log.DebugLog.Println("No surf middleware called")
 	csrfHandler := nosurf.New(next)
 	secure, _ := strconv.ParseBool(s.env.cookie.secure)
 
@@ -59,17 +68,58 @@ func (s *Socle) NoSurfMiddleware(next http.Handler) http.Handler {
 	return csrfHandler
 }
 
+// MaintenanceModeCheckMiddleware answers 503 to the requests received during the maintenance
+// mode, with a Retry-After header matching its expected end. Visiting /<secret> sets a
+// cookie letting the browser through. The allowed addresses are trusted from the headers
+// of the request after the real_ip middleware, see inMaintenance.
 func (s *Socle) MaintenanceModeCheckMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if maintenanceMode {
-			if !strings.Contains(r.URL.Path, "/public/maintenance.html") {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				w.Header().Set("Retry-After:", "300")
-				w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
-				http.ServeFile(w, r, fmt.Sprintf("%s/public/maintenance.html", s.RootPath))
-				return
-			}
+		st := s.Maintenance.Current()
+		if !st.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if st.Secret != "" && r.URL.Path == "/"+st.Secret {
+			http.SetCookie(w, &http.Cookie{
+				Name:     maintenanceCookie,
+				Value:    st.BypassToken(),
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		if !s.inMaintenance(r, st) || strings.Contains(r.URL.Path, "/public/maintenance.html") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := st.Message
+		if message == "" {
+			message = "Server in maintenance mode"
+		}
+		retryAfter := int(math.Ceil(st.RetryAfter(time.Now()).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
+
+		if wantsJSON(r) {
+			_ = s.WriteJSON(w, http.StatusServiceUnavailable, map[string]any{
+				"error":   true,
+				"message": message,
+			})
+			return
+		}
+
+		page, err := os.ReadFile(fmt.Sprintf("%s/public/maintenance.html", s.RootPath))
+		if err != nil {
+			http.Error(w, message, http.StatusServiceUnavailable)
+			return
 		}
-		next.ServeHTTP(w, r)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write(page)
 	})
 }
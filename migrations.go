@@ -1,8 +1,6 @@
 package socle
 
 import (
-	"log"
-
 	"github.com/gobuffalo/pop"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -89,7 +87,7 @@ func (c *Socle) MigrateUp(dsn string) error {
 	defer m.Close()
 
 	if err := m.Up(); err != nil {
-		log.Println("Error running migration:", err)
+		c.Log.ErrorLog.Println("Error running migration:", err)
 		return err
 	}
 	return nil
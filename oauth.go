@@ -0,0 +1,164 @@
+package socle
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/socle/pkg/oauth"
+)
+
+// Session keys of the authorization in progress.
+const (
+	oauthProviderKey = "oauth_provider"
+	oauthStateKey    = "oauth_state"
+	oauthVerifierKey = "oauth_verifier"
+	oauthNonceKey    = "oauth_nonce"
+)
+
+// initOAuth creates the providers of the oauth section of socle.yaml, discovering the
+// OIDC ones, and mounts their login and callback routes on the web entry:
+// <path>/<provider>/login and <path>/<provider>/callback.
+func (s *Socle) initOAuth() error {
+	cfg := s.appConfig.OAuth
+	web := s.Entry("web")
+	if len(cfg.Providers) == 0 || web == nil {
+		return nil
+	}
+
+	path := strings.TrimSuffix(cfg.Path, "/")
+	if path == "" {
+		path = "/auth"
+	}
+	configs := make([]oauth.Config, 0, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		configs = append(configs, oauth.Config{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  strings.TrimSuffix(cfg.RedirectBase, "/") + path + "/" + name + "/callback",
+			Scopes:       p.Scopes,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			UserInfoURL:  p.UserInfoURL,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	providers, err := oauth.NewProviders(ctx, configs)
+	if err != nil {
+		return err
+	}
+	s.OAuth = providers
+	if s.OAuthUsers == nil && s.DB.Pool != nil {
+		users := oauth.NewSQLUsers(s.DB.Pool, s.DB.DBType)
+		for name, p := range cfg.Providers {
+			users.TrustEmail[name] = p.TrustEmail
+		}
+		s.OAuthUsers = users
+	}
+
+	// the users log in on these routes, they are not behind the authentication middlewares
+	web.mountPublic(func(r chi.Router) {
+		r.Get(path+"/{provider}/login", s.OAuthLoginHandler)
+		r.Get(path+"/{provider}/callback", s.OAuthCallbackHandler)
+	})
+	return nil
+}
+
+// OAuthLoginHandler redirects to the provider of the route, keeping the state, the PKCE
+// verifier and the nonce of the authorization in the session.
+func (s *Socle) OAuthLoginHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, err := s.OAuth.Get(name)
+	if err != nil {
+		s.Error404(w, r)
+		return
+	}
+
+	flow, err := oauth.NewFlow()
+	if err != nil {
+		s.Error500(w, r)
+		return
+	}
+	ctx := r.Context()
+	s.Session.Put(ctx, oauthProviderKey, name)
+	s.Session.Put(ctx, oauthStateKey, flow.State)
+	s.Session.Put(ctx, oauthVerifierKey, flow.Verifier)
+	s.Session.Put(ctx, oauthNonceKey, flow.Nonce)
+
+	http.Redirect(w, r, provider.AuthCodeURL(flow), http.StatusFound)
+}
+
+// OAuthCallbackHandler completes the authorization: it checks the state, exchanges the
+// code, maps the identity onto a user with OAuthUsers and logs it in the session.
+func (s *Socle) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := chi.URLParam(r, "provider")
+	flow := oauth.Flow{
+		State:    s.Session.PopString(ctx, oauthStateKey),
+		Verifier: s.Session.PopString(ctx, oauthVerifierKey),
+		Nonce:    s.Session.PopString(ctx, oauthNonceKey),
+	}
+	started := s.Session.PopString(ctx, oauthProviderKey)
+
+	provider, err := s.OAuth.Get(name)
+	if err != nil {
+		s.Error404(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		s.oauthFailed(w, r, name, "provider refused: "+e)
+		return
+	}
+	if started != name || flow.State == "" || subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow.State)) != 1 {
+		s.oauthFailed(w, r, name, "state mismatch")
+		return
+	}
+
+	id, _, err := provider.Exchange(ctx, flow, q.Get("code"))
+	if err != nil {
+		s.oauthFailed(w, r, name, err.Error())
+		return
+	}
+	if s.OAuthUsers == nil {
+		s.oauthFailed(w, r, name, "no user mapper, set OAuthUsers")
+		return
+	}
+	userID, err := s.OAuthUsers.MapUser(ctx, id)
+	if err != nil {
+		s.oauthFailed(w, r, name, err.Error())
+		return
+	}
+
+	if err := s.Login(ctx, userID); err != nil {
+		s.Error500(w, r)
+		return
+	}
+	s.Logger.Info("oauth login", "provider", name, "subject", id.Subject, "user_id", userID)
+
+	after := s.appConfig.OAuth.AfterLogin
+	if after == "" {
+		after = "/"
+	}
+	http.Redirect(w, r, after, http.StatusSeeOther)
+}
+
+// oauthFailed logs the failure and redirects to the login page with an error message.
+func (s *Socle) oauthFailed(w http.ResponseWriter, r *http.Request, provider, reason string) {
+	s.Logger.Warn("oauth login failed", "provider", provider, "reason", reason)
+	s.Session.Put(r.Context(), "error", "Unable to sign in with "+provider)
+
+	page := s.appConfig.OAuth.LoginPage
+	if page == "" {
+		page = "/users/login"
+	}
+	http.Redirect(w, r, page, http.StatusSeeOther)
+}
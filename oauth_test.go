@@ -0,0 +1,168 @@
+package socle
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/socle/pkg/oauth"
+	"github.com/socle-framework/socle/pkg/oauth/oidctest"
+)
+
+// mapUser adapts a function to oauth.UserMapper.
+type mapUser func(ctx context.Context, id *oauth.Identity) (int, error)
+
+func (f mapUser) MapUser(ctx context.Context, id *oauth.Identity) (int, error) { return f(ctx, id) }
+
+// oauthApp serves the login and callback routes of the provider "test", backed by an
+// oidctest provider, and /me returning the user logged in the session.
+func oauthApp(t *testing.T) (*httptest.Server, *http.Client) {
+	t.Helper()
+	idp := oidctest.NewServer("client", "secret")
+	t.Cleanup(idp.Close)
+
+	s := &Socle{
+		Session: scs.New(),
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		OAuthUsers: mapUser(func(ctx context.Context, id *oauth.Identity) (int, error) {
+			return 42, nil
+		}),
+	}
+	r := chi.NewRouter()
+	r.Use(s.Session.LoadAndSave)
+	r.Get("/auth/{provider}/login", s.OAuthLoginHandler)
+	r.Get("/auth/{provider}/callback", s.OAuthCallbackHandler)
+	r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := s.SessionUserID(r.Context())
+		_, _ = w.Write([]byte(strconv.Itoa(id)))
+	})
+	app := httptest.NewServer(r)
+	t.Cleanup(app.Close)
+
+	providers, err := oauth.NewProviders(context.Background(), []oauth.Config{{
+		Name:         "test",
+		Issuer:       idp.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  app.URL + "/auth/test/callback",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.OAuth = providers
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return app, client
+}
+
+// redirect requests target and returns the Location of the redirection it answers.
+func redirect(t *testing.T, client *http.Client, target string) *url.URL {
+	t.Helper()
+	res, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	loc, err := res.Location()
+	if err != nil {
+		t.Fatalf("GET %s: %d without redirection", target, res.StatusCode)
+	}
+	return loc
+}
+
+// withQuery returns u with the query parameter name set to value.
+func withQuery(u *url.URL, name, value string) string {
+	q := u.Query()
+	q.Set(name, value)
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func TestOAuthLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the redirection to the provider, or to the callback
+		authorize func(u *url.URL) string
+		callback  func(u *url.URL) string
+		loggedIn  bool
+	}{
+		{name: "granted", loggedIn: true},
+		{
+			name:     "state mismatch",
+			callback: func(u *url.URL) string { return withQuery(u, "state", "forged") },
+		},
+		{
+			name:      "nonce mismatch",
+			authorize: func(u *url.URL) string { return withQuery(u, "nonce", "replayed") },
+		},
+		{
+			name:      "PKCE challenge mismatch",
+			authorize: func(u *url.URL) string { return withQuery(u, "code_challenge", "c2VjcmV0") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, client := oauthApp(t)
+
+			authorize := redirect(t, client, app.URL+"/auth/test/login")
+			for _, p := range []string{"state", "nonce", "code_challenge"} {
+				if authorize.Query().Get(p) == "" {
+					t.Fatalf("authorization request without %s: %s", p, authorize)
+				}
+			}
+			target := authorize.String()
+			if tt.authorize != nil {
+				target = tt.authorize(authorize)
+			}
+
+			callback := redirect(t, client, target)
+			target = callback.String()
+			if tt.callback != nil {
+				target = tt.callback(callback)
+			}
+
+			after := redirect(t, client, target)
+			want := "/users/login"
+			if tt.loggedIn {
+				want = "/"
+			}
+			if after.Path != want {
+				t.Errorf("redirected to %s, want %s", after.Path, want)
+			}
+
+			res, err := client.Get(app.URL + "/me")
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if got := string(body) == "42"; got != tt.loggedIn {
+				t.Errorf("logged in = %v, want %v", got, tt.loggedIn)
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackWithoutLogin(t *testing.T) {
+	app, client := oauthApp(t)
+
+	after := redirect(t, client, app.URL+"/auth/test/callback?code=code&state=state")
+	if after.Path != "/users/login" {
+		t.Errorf("redirected to %s, want /users/login", after.Path)
+	}
+}
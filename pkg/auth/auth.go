@@ -1,10 +1,20 @@
 package auth
 
 import (
+	"context"
 	"time"
 )
 
 type Authenticator interface {
-	GenerateToken(username string, duration time.Duration, issuer string) (string, *Payload, error)
+	GenerateToken(username string, duration time.Duration, issuer string, opts ...TokenOption) (string, *Payload, error)
 	ValidateToken(token string) (*Payload, error)
 }
+
+// RefreshAuthenticator is an Authenticator rotating refresh tokens and revoking tokens.
+type RefreshAuthenticator interface {
+	Authenticator
+	GenerateRefreshToken(ctx context.Context, username string, duration time.Duration, issuer, family string, opts ...TokenOption) (string, *Payload, error)
+	Refresh(ctx context.Context, token string, duration, refreshDuration time.Duration, issuer string) (*TokenPair, error)
+	Revoke(ctx context.Context, token string) error
+	RevokeUser(ctx context.Context, username string, duration time.Duration) error
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// BadgerStore stores the tokens in badger entries expiring with them, for single node
+// deployments.
+type BadgerStore struct {
+	DB *badger.DB
+}
+
+// NewBadgerStore creates a store using db.
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{DB: db}
+}
+
+const (
+	badgerRefreshPrefix = "tokens:refresh:"
+	badgerFamilyPrefix  = "tokens:family:"
+	badgerUserPrefix    = "tokens:user:"
+	badgerRevokedPrefix = "tokens:revoked:"
+	badgerCutoffPrefix  = "tokens:cutoff:"
+)
+
+// update runs fn in a read-write transaction, retrying when it conflicts with another one.
+func (s *BadgerStore) update(fn func(txn *badger.Txn) error) error {
+	for {
+		err := s.DB.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+}
+
+// setUntil sets key to value until expires.
+func setUntil(txn *badger.Txn, key string, value []byte, expires time.Time) error {
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return nil
+	}
+	return txn.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(ttl))
+}
+
+func getJSON(txn *badger.Txn, key string, v any) error {
+	item, err := txn.Get([]byte(key))
+	if err != nil {
+		return err
+	}
+	return item.Value(func(data []byte) error {
+		return json.Unmarshal(data, v)
+	})
+}
+
+func setRefresh(txn *badger.Txn, t RefreshToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return setUntil(txn, badgerRefreshPrefix+t.ID, data, t.ExpiresAt)
+}
+
+// index adds id to the list of tokens of key, kept until the last of them expires.
+func index(txn *badger.Txn, key, id string, expires time.Time) error {
+	var ids []string
+	item, err := txn.Get([]byte(key))
+	switch {
+	case err == nil:
+		if last := time.Unix(int64(item.ExpiresAt()), 0); last.After(expires) {
+			expires = last
+		}
+		err = item.Value(func(data []byte) error {
+			return json.Unmarshal(data, &ids)
+		})
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, badger.ErrKeyNotFound):
+		return err
+	}
+
+	data, err := json.Marshal(append(ids, id))
+	if err != nil {
+		return err
+	}
+	return setUntil(txn, key, data, expires)
+}
+
+func (s *BadgerStore) SaveRefresh(ctx context.Context, t RefreshToken) error {
+	return s.update(func(txn *badger.Txn) error {
+		if err := setRefresh(txn, t); err != nil {
+			return err
+		}
+		if err := index(txn, badgerFamilyPrefix+t.Family, t.ID, t.ExpiresAt); err != nil {
+			return err
+		}
+		return index(txn, badgerUserPrefix+t.Username, t.ID, t.ExpiresAt)
+	})
+}
+
+func (s *BadgerStore) ConsumeRefresh(ctx context.Context, id string) (RefreshToken, error) {
+	var t RefreshToken
+	err := s.update(func(txn *badger.Txn) error {
+		t = RefreshToken{}
+		err := getJSON(txn, badgerRefreshPrefix+id, &t)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if t.Revoked {
+			return ErrTokenRevoked
+		}
+		if t.Used {
+			return ErrTokenReused
+		}
+
+		used := t
+		used.Used = true
+		return setRefresh(txn, used)
+	})
+	return t, err
+}
+
+// revokeIndex revokes the refresh tokens listed in key.
+func (s *BadgerStore) revokeIndex(key string) error {
+	return s.update(func(txn *badger.Txn) error {
+		var ids []string
+		err := getJSON(txn, key, &ids)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			var t RefreshToken
+			err := getJSON(txn, badgerRefreshPrefix+id, &t)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			t.Revoked = true
+			if err := setRefresh(txn, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BadgerStore) RevokeFamily(ctx context.Context, family string) error {
+	return s.revokeIndex(badgerFamilyPrefix + family)
+}
+
+func (s *BadgerStore) Revoke(ctx context.Context, jti string, expires time.Time) error {
+	return s.update(func(txn *badger.Txn) error {
+		return setUntil(txn, badgerRevokedPrefix+jti, nil, expires)
+	})
+}
+
+func (s *BadgerStore) RevokeUser(ctx context.Context, username string, at, expires time.Time) error {
+	if err := s.revokeIndex(badgerUserPrefix + username); err != nil {
+		return err
+	}
+	return s.update(func(txn *badger.Txn) error {
+		return setUntil(txn, badgerCutoffPrefix+username, []byte(strconv.FormatInt(at.UnixMilli(), 10)), expires)
+	})
+}
+
+func (s *BadgerStore) Revoked(ctx context.Context, jti, username string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := s.DB.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(badgerRevokedPrefix + jti))
+		if err == nil {
+			revoked = true
+			return nil
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		item, err := txn.Get([]byte(badgerCutoffPrefix + username))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(data []byte) error {
+			cutoff, err := strconv.ParseInt(string(data), 10, 64)
+			if err != nil {
+				return err
+			}
+			revoked = revokedBefore(issuedAt, time.UnixMilli(cutoff))
+			return nil
+		})
+	})
+	return revoked, err
+}
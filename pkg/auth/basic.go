@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// BasicUsers checks the credentials of HTTP Basic authentication against the password
+// hashes of its users.
+type BasicUsers map[string]string
+
+// dummyHash is checked for unknown users, so that they take as long to refuse as the
+// known ones and cannot be told apart.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("socle")
+	return hash
+})
+
+// LoadBasicUsers reads the users of an htpasswd like file: one user:hash line per user,
+// with a bcrypt or argon2id hash. Empty lines and lines starting with # are skipped.
+func LoadBasicUsers(path string) (BasicUsers, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := BasicUsers{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, found := strings.Cut(line, ":")
+		if !found || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, n)
+		}
+		if !knownHash(hash) {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, ErrUnknownHash)
+		}
+		users[user] = hash
+	}
+	return users, scanner.Err()
+}
+
+// Check reports whether password is the password of user.
+func (u BasicUsers) Check(user, password string) bool {
+	hash, ok := u[user]
+	if !ok {
+		_, _ = CheckPassword(dummyHash(), password)
+		return false
+	}
+	valid, err := CheckPassword(hash, password)
+	return err == nil && valid
+}
+
+// CheckPlain reports in constant time whether the credentials are user and password.
+func CheckPlain(user, password, wantUser, wantPassword string) bool {
+	// compare digests, so that the lengths do not leak either
+	u, wu := sha256.Sum256([]byte(user)), sha256.Sum256([]byte(wantUser))
+	p, wp := sha256.Sum256([]byte(password)), sha256.Sum256([]byte(wantPassword))
+	userOK := subtle.ConstantTimeCompare(u[:], wu[:])
+	passOK := subtle.ConstantTimeCompare(p[:], wp[:])
+	return userOK&passOK == 1
+}
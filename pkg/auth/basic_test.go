@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(hash)
+}
+
+func TestLoadBasicUsers(t *testing.T) {
+	ada := bcryptHash(t, "lovelace")
+	alan, err := HashPassword("turing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		users   []string
+		wantErr string
+	}{
+		{name: "bcrypt and argon2id", file: "ada:" + ada + "\nalan:" + alan + "\n", users: []string{"ada", "alan"}},
+		{name: "comments and blank lines", file: "# users\n\n  ada:" + ada + "  \n", users: []string{"ada"}},
+		{name: "empty", file: "", users: nil},
+		{name: "no separator", file: "ada " + ada, wantErr: ":1: expected user:hash"},
+		{name: "no user", file: "# users\n:" + ada, wantErr: ":2: expected user:hash"},
+		{name: "plain password", file: "ada:lovelace", wantErr: ErrUnknownHash.Error()},
+		{name: "md5 apr1 hash", file: "ada:$apr1$xyz$abc", wantErr: ErrUnknownHash.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			users, err := LoadBasicUsers(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != len(tt.users) {
+				t.Fatalf("users = %v, want %v", users, tt.users)
+			}
+			for _, u := range tt.users {
+				if _, ok := users[u]; !ok {
+					t.Errorf("user %s missing", u)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadBasicUsers(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestBasicUsersCheck(t *testing.T) {
+	alan, err := HashPassword("turing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := BasicUsers{"ada": bcryptHash(t, "lovelace"), "alan": alan}
+
+	tests := []struct {
+		user, password string
+		ok             bool
+	}{
+		{"ada", "lovelace", true},
+		{"ada", "Lovelace", false},
+		{"ada", "", false},
+		{"alan", "turing", true},
+		{"alan", "lovelace", false},
+		{"grace", "hopper", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := users.Check(tt.user, tt.password); got != tt.ok {
+			t.Errorf("Check(%q, %q) = %v, want %v", tt.user, tt.password, got, tt.ok)
+		}
+	}
+}
+
+func TestCheckPlain(t *testing.T) {
+	tests := []struct {
+		user, password string
+		ok             bool
+	}{
+		{"admin", "s3cret", true},
+		{"admin", "s3cret ", false},
+		{"admin", "s3cre", false},
+		{"Admin", "s3cret", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := CheckPlain(tt.user, tt.password, "admin", "s3cret"); got != tt.ok {
+			t.Errorf("CheckPlain(%q, %q) = %v, want %v", tt.user, tt.password, got, tt.ok)
+		}
+	}
+}
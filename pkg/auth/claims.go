@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// registeredClaims are the claims set by the authenticator, which custom claims cannot
+// override.
+var registeredClaims = []string{
+	"sub", "exp", "iat", "nbf", "iss", "aud", "jti", "id", "typ", "fam",
+	"roles", "scope", "scp", "tenant",
+}
+
+// TokenOption sets the roles, scopes, tenant or custom claims of a generated token.
+type TokenOption func(*Payload)
+
+// WithRoles grants roles to the token.
+func WithRoles(roles ...string) TokenOption {
+	return func(p *Payload) {
+		p.Roles = append(p.Roles, roles...)
+	}
+}
+
+// WithScopes grants scopes to the token.
+func WithScopes(scopes ...string) TokenOption {
+	return func(p *Payload) {
+		p.Scopes = append(p.Scopes, scopes...)
+	}
+}
+
+// WithTenant sets the tenant the token belongs to.
+func WithTenant(tenant string) TokenOption {
+	return func(p *Payload) {
+		p.Tenant = tenant
+	}
+}
+
+// WithAudience sets the audience of the token, instead of the one of the authenticator.
+func WithAudience(audience ...string) TokenOption {
+	return func(p *Payload) {
+		p.Audience = audience
+	}
+}
+
+// WithClaim adds the custom claim name to the token. value must marshal to JSON.
+func WithClaim(name string, value any) TokenOption {
+	return func(p *Payload) {
+		if p.Claims == nil {
+			p.Claims = map[string]any{}
+		}
+		p.Claims[name] = value
+	}
+}
+
+// WithClaims adds the custom claims to the token.
+func WithClaims(claims map[string]any) TokenOption {
+	return func(p *Payload) {
+		for name, value := range claims {
+			WithClaim(name, value)(p)
+		}
+	}
+}
+
+// options returns the options generating a token with the same claims as payload, to
+// carry them over when a refresh token is exchanged.
+func (payload *Payload) options() []TokenOption {
+	return []TokenOption{
+		WithRoles(payload.Roles...),
+		WithScopes(payload.Scopes...),
+		WithTenant(payload.Tenant),
+		WithAudience(payload.Audience...),
+		WithClaims(payload.Claims),
+	}
+}
+
+// Claim returns the custom claim name of the token.
+func (payload *Payload) Claim(name string) (any, bool) {
+	value, ok := payload.Claims[name]
+	return value, ok
+}
+
+// ClaimString returns the custom claim name when it is a string, or "".
+func (payload *Payload) ClaimString(name string) string {
+	s, _ := payload.Claims[name].(string)
+	return s
+}
+
+// ClaimAs returns the custom claim name of payload decoded as a T. The claims of a
+// validated token come from JSON, so their numbers are float64 and their objects maps:
+//
+//	orgID, ok := auth.ClaimAs[int64](payload, "org_id")
+func ClaimAs[T any](payload *Payload, name string) (T, bool) {
+	var v T
+	value, ok := payload.Claims[name]
+	if !ok {
+		return v, false
+	}
+	if typed, ok := value.(T); ok {
+		return typed, true
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return v, false
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, false
+	}
+	return v, true
+}
+
+// DecodeClaims decodes the custom claims of payload into v, a pointer to a struct with
+// json tags.
+func (payload *Payload) DecodeClaims(v any) error {
+	data, err := json.Marshal(payload.Claims)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// customClaims returns the claims not set by the authenticator.
+func customClaims(claims map[string]any) map[string]any {
+	custom := maps.Clone(claims)
+	maps.DeleteFunc(custom, func(name string, _ any) bool {
+		return slices.Contains(registeredClaims, name)
+	})
+	if len(custom) == 0 {
+		return nil
+	}
+	return custom
+}
+
+// scopeClaim returns scopes as the space separated scope claim of OAuth (RFC 8693).
+func scopeClaim(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,59 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+type apiKeyContextKey struct{}
+
+// NewContext returns a copy of ctx carrying the payload of the authenticated token.
+func NewContext(ctx context.Context, payload *Payload) context.Context {
+	return context.WithValue(ctx, contextKey{}, payload)
+}
+
+// FromContext returns the payload stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (*Payload, bool) {
+	payload, ok := ctx.Value(contextKey{}).(*Payload)
+	return payload, ok
+}
+
+// Username returns the username of the token authenticated in ctx, or "" if none.
+func Username(ctx context.Context) string {
+	if payload, ok := FromContext(ctx); ok {
+		return payload.Username
+	}
+	return ""
+}
+
+// WithAPIKey returns a copy of ctx carrying the id of the API key the request was
+// authenticated with. The middleware checking the API keys of the app calls it once the
+// key is verified, so that the api_key rate limits count the requests of the key.
+func WithAPIKey(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, id)
+}
+
+// APIKey returns the id of the API key stored in ctx by WithAPIKey, or "" if none.
+func APIKey(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return id
+}
+
+// HasRole reports whether the token authenticated in ctx grants role.
+func HasRole(ctx context.Context, role string) bool {
+	payload, ok := FromContext(ctx)
+	return ok && payload.HasRole(role)
+}
+
+// HasScope reports whether the token authenticated in ctx grants scope.
+func HasScope(ctx context.Context, scope string) bool {
+	payload, ok := FromContext(ctx)
+	return ok && payload.HasScope(scope)
+}
+
+// Tenant returns the tenant of the token authenticated in ctx, or "" if none.
+func Tenant(ctx context.Context) string {
+	if payload, ok := FromContext(ctx); ok {
+		return payload.Tenant
+	}
+	return ""
+}
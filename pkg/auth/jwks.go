@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served on /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// JWK returns the public part of k. HMAC keys have none.
+func (k *Key) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64.EncodeToString(pub.N.Bytes())
+		jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = b64.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// JWKS returns the public keys of the set, so that other services can verify its tokens.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.Keys() {
+		if jwk, ok := k.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// Key returns the verifying key of jwk, for its alg when set.
+func (jwk JWK) Key() (*Key, error) {
+	k, err := jwk.key()
+	if err != nil {
+		return nil, err
+	}
+	if m := jwt.GetSigningMethod(jwk.Alg); m != nil {
+		k.Method = m
+	}
+	return k, nil
+}
+
+func (jwk JWK) key() (*Key, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(jwk.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwk %s: unsupported curve %q", jwk.Kid, jwk.Crv)
+		}
+		x, err := b64.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("jwk %s: point not on curve", jwk.Kid)
+		}
+		return NewKey(jwk.Kid, pub)
+
+	case "OKP":
+		x, err := b64.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %s: unsupported curve %q", jwk.Kid, jwk.Crv)
+		}
+		return NewKey(jwk.Kid, ed25519.PublicKey(x))
+	}
+	return nil, fmt.Errorf("jwk %s: unsupported key type %q", jwk.Kid, jwk.Kty)
+}
+
+// RemoteKeySet resolves the keys of a JWKS served by another service, or stored in a
+// local file. The set is fetched again every Refresh, and when a token is signed by an
+// unknown key, at most once per minute.
+type RemoteKeySet struct {
+	Source  string
+	Refresh time.Duration
+	Client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*Key
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet returns the key set of source, a http(s) URL or a file path.
+func NewRemoteKeySet(source string, refresh time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		Source:  source,
+		Refresh: refresh,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the key identified by kid, fetching the set if needed. Tokens verified
+// with a remote set must have a kid.
+func (rs *RemoteKeySet) Key(kid string) (*Key, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	stale := time.Since(rs.fetchedAt) > rs.Refresh
+	k, ok := rs.keys[kid]
+	if !ok && time.Since(rs.fetchedAt) > time.Minute {
+		stale = true
+	}
+
+	if stale {
+		keys, err := rs.fetch()
+		// on failure, keep the keys fetched before and wait before trying again
+		rs.fetchedAt = time.Now()
+		if err != nil && rs.keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			rs.keys = keys
+		}
+		k, ok = rs.keys[kid]
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+func (rs *RemoteKeySet) fetch() (map[string]*Key, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(rs.Source, "http://") || strings.HasPrefix(rs.Source, "https://") {
+		data, err = rs.get()
+	} else {
+		data, err = os.ReadFile(rs.Source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks %s: %w", rs.Source, err)
+	}
+
+	keys := make(map[string]*Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		k, err := jwk.Key()
+		if err != nil {
+			// skip the keys we cannot use, the others are still valid
+			continue
+		}
+		keys[k.ID] = k
+	}
+	return keys, nil
+}
+
+func (rs *RemoteKeySet) get() ([]byte, error) {
+	resp, err := rs.Client.Get(rs.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("jwks " + rs.Source + ": " + resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
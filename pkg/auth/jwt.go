@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,40 +13,100 @@ import (
 )
 
 type JWTAuthenticator struct {
-	secret string
+	signer *Key
+	keys   KeyResolver
 	aud    string
 	iss    string
+
+	// Store persists the refresh tokens and the revoked tokens. Without a store, the
+	// tokens cannot be revoked and the refresh tokens are not rotated.
+	Store TokenStore
 }
 
+// NewJWTAuthenticator returns an authenticator signing and verifying HS256 tokens with secret.
 func NewJWTAuthenticator(secret, aud, iss string) *JWTAuthenticator {
-	return &JWTAuthenticator{secret, iss, aud}
+	key := NewHMACKey("", []byte(secret))
+	return NewJWTAuthenticatorWithKeys(NewKeySet(key), aud, iss)
 }
 
-func (a *JWTAuthenticator) GenerateToken(username string, duration time.Duration, issuer string) (string, *Payload, error) {
+// NewJWTAuthenticatorWithKeys returns an authenticator signing the tokens with the current
+// key of keys, and verifying them with the key of their kid. keys can be nil for an
+// authenticator only verifying tokens, with the resolvers.
+func NewJWTAuthenticatorWithKeys(keys *KeySet, aud, iss string, resolvers ...KeyResolver) *JWTAuthenticator {
+	a := &JWTAuthenticator{aud: aud, iss: iss}
+	if keys != nil {
+		a.signer = keys.Current
+		resolvers = append([]KeyResolver{keys}, resolvers...)
+	}
+	a.keys = Resolvers(resolvers)
+	return a
+}
 
-	payload, err := NewPayload(username, duration)
+// GenerateToken returns an access token of username, with the roles, scopes, tenant and
+// custom claims of opts.
+func (a *JWTAuthenticator) GenerateToken(username string, duration time.Duration, issuer string, opts ...TokenOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration, opts...)
 	if err != nil {
 		return "", payload, err
 	}
 
-	claims := jwt.MapClaims{
-		"sub": username,
-		"exp": payload.ExpiredAt.Unix(),
-		"iat": payload.IssuedAt.Unix(),
-		"nbf": payload.IssuedAt.Unix(),
-		"iss": issuer,
-		"aud": issuer,
-		"id":  payload.ID,
+	tokenString, err := a.sign(payload, issuer, nil)
+	if err != nil {
+		return "", payload, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return tokenString, payload, nil
+}
 
-	tokenString, err := token.SignedString([]byte(a.secret))
+// sign returns the token of payload, with the extra claims.
+func (a *JWTAuthenticator) sign(payload *Payload, issuer string, extra jwt.MapClaims) (string, error) {
+	if a.signer == nil {
+		return "", ErrNoSigningKey
+	}
+	signingKey, err := a.signer.signingKey()
 	if err != nil {
-		return "", payload, err
+		return "", err
 	}
 
-	return tokenString, payload, nil
+	// les claims personnalisés ne remplacent pas ceux de l'authenticator
+	claims := jwt.MapClaims(customClaims(payload.Claims))
+	if claims == nil {
+		claims = jwt.MapClaims{}
+	}
+	claims["sub"] = payload.Username
+	claims["exp"] = payload.ExpiredAt.Unix()
+	// au millième de seconde, pour distinguer les tokens émis dans la seconde d'une révocation
+	claims["iat"] = float64(payload.IssuedAt.UnixMilli()) / 1000
+	claims["nbf"] = payload.IssuedAt.Unix()
+	claims["iss"] = issuer
+	claims["jti"] = payload.ID.String()
+
+	switch {
+	case len(payload.Audience) > 0:
+		claims["aud"] = payload.Audience
+	case a.aud != "":
+		claims["aud"] = a.aud
+	default:
+		claims["aud"] = issuer
+	}
+	if len(payload.Roles) > 0 {
+		claims["roles"] = payload.Roles
+	}
+	if len(payload.Scopes) > 0 {
+		claims["scope"] = scopeClaim(payload.Scopes)
+	}
+	if payload.Tenant != "" {
+		claims["tenant"] = payload.Tenant
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(a.signer.Method, claims)
+	if a.signer.ID != "" {
+		token.Header["kid"] = a.signer.ID
+	}
+	return token.SignedString(signingKey)
 }
 
 // func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
@@ -61,52 +124,118 @@ func (a *JWTAuthenticator) GenerateToken(username string, duration time.Duration
 // 	)
 // }
 
+// ValidateToken validates an access token, and checks that it is not revoked when the
+// authenticator has a Store.
 func (a *JWTAuthenticator) ValidateToken(token string) (*Payload, error) {
-	// Fonction de validation du type de méthode de signature
+	payload, claims, err := a.parse(token)
+	if err != nil {
+		return nil, err
+	}
+	// un refresh token ne donne pas accès à l'api
+	if claims["typ"] == refreshType {
+		return nil, ErrInvalidToken
+	}
+
+	if a.Store != nil {
+		revoked, err := a.Store.Revoked(context.Background(), payload.ID.String(), payload.Username, payload.IssuedAt)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return payload, nil
+}
+
+// parse verifies the signature and the registered claims of token, and returns its payload.
+func (a *JWTAuthenticator) parse(token string) (*Payload, jwt.MapClaims, error) {
+	// la clé est choisie d'après le kid du token
 	keyFunc := func(t *jwt.Token) (any, error) {
-		if err := validateSigningMethod(t); err != nil {
+		kid, _ := t.Header["kid"].(string)
+		key, err := a.keys.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateSigningMethod(t, key); err != nil {
 			return nil, err
 		}
-		return []byte(a.secret), nil
+		return key.verifyKey(), nil
 	}
 
 	jwtToken, err := jwt.Parse(token, keyFunc,
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.aud),
-		jwt.WithIssuer(a.aud),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods(validMethods),
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	claims, _ := jwtToken.Claims.(jwt.MapClaims)
 
-	iat, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["iat"]), 10, 64)
+	iat, err := strconv.ParseFloat(fmt.Sprint(claims["iat"]), 64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	exp, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["exp"]), 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// les tokens émis avant le claim jti portaient leur identifiant dans id
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		jti, _ = claims["id"].(string)
 	}
-	//fmt.Printf("**********************   %v     **************************\n", claims)
-	//uuid.Must(uuid.Parse(fmt.Sprintf("%.f", claims["id"])))
-	tokenID, err := uuid.NewRandom()
+	tokenID, err := uuid.Parse(jti)
 	if err != nil {
-		return nil, err
+		return nil, nil, ErrInvalidToken
 	}
+
+	family, _ := claims["fam"].(string)
+	tenant, _ := claims["tenant"].(string)
+	audience, _ := claims.GetAudience()
 	return &Payload{
 		ID:        tokenID,
 		Username:  fmt.Sprintf("%s", claims["sub"]),
-		IssuedAt:  time.Unix(iat, 0),
+		Roles:     listClaim(claims, "roles"),
+		Scopes:    append(listClaim(claims, "scope"), listClaim(claims, "scp")...),
+		Tenant:    tenant,
+		Audience:  audience,
+		Family:    family,
+		IssuedAt:  time.UnixMilli(int64(math.Round(iat * 1000))),
 		ExpiredAt: time.Unix(exp, 0),
-	}, nil
+		Claims:    customClaims(claims),
+	}, claims, nil
 }
 
-// Fonction séparée pour valider la méthode de signature
-func validateSigningMethod(t *jwt.Token) error {
-	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+// listClaim returns the claim name, a JSON array or a space separated string like the
+// OAuth scope claim, as a list.
+func listClaim(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// validMethods are the signing methods accepted, the key of the token restricting them further.
+var validMethods = []string{"HS256", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Fonction séparée pour valider la méthode de signature: elle doit être celle de la clé,
+// sans quoi une clé publique RSA pourrait servir de secret HMAC
+func validateSigningMethod(t *jwt.Token, key *Key) error {
+	if t.Method.Alg() != key.Method.Alg() {
 		return fmt.Errorf("unexpected signing method %v", t.Header["alg"])
 	}
 	return nil
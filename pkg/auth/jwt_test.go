@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newKey(t *testing.T, id string, key any) *Key {
+	t.Helper()
+	k, err := NewKey(id, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestValidateToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := newKey(t, "k2", ecKey)
+	previous := newKey(t, "k1", rsaKey)
+	verifier := NewJWTAuthenticatorWithKeys(NewKeySet(current, previous), "api", "socle")
+
+	hmac := NewJWTAuthenticator(testSecret, "api", "socle")
+	// a token signed by a key unknown to the verifier under the kid of a known one
+	forged := NewJWTAuthenticatorWithKeys(NewKeySet(NewHMACKey("k2", []byte(testSecret))), "api", "socle")
+	// HS256 with the public RSA key as secret, verified with the RSA key
+	confused := NewJWTAuthenticatorWithKeys(NewKeySet(NewHMACKey("k1", rsaPub)), "api", "socle")
+
+	tests := []struct {
+		name     string
+		signer   *JWTAuthenticator
+		verifier *JWTAuthenticator
+		duration time.Duration
+		issuer   string
+		opts     []TokenOption
+		wantErr  bool
+		wantAlg  string
+		check    func(t *testing.T, p *Payload)
+	}{
+		{name: "hmac", signer: hmac, verifier: hmac, duration: time.Hour, issuer: "socle"},
+		{name: "es384", signer: verifier, verifier: verifier, duration: time.Hour, issuer: "socle"},
+		{name: "previous rsa key", signer: NewJWTAuthenticatorWithKeys(NewKeySet(previous), "api", "socle"), verifier: verifier, duration: time.Hour, issuer: "socle"},
+		{name: "eddsa", signer: NewJWTAuthenticatorWithKeys(NewKeySet(newKey(t, "ed", edKey)), "api", "socle"), verifier: NewJWTAuthenticatorWithKeys(NewKeySet(newKey(t, "ed", edKey.Public())), "api", "socle"), duration: time.Hour, issuer: "socle"},
+		{name: "expired", signer: hmac, verifier: hmac, duration: -time.Minute, issuer: "socle", wantErr: true},
+		{name: "other issuer", signer: hmac, verifier: hmac, duration: time.Hour, issuer: "evil", wantErr: true},
+		{name: "other audience", signer: hmac, verifier: hmac, duration: time.Hour, issuer: "socle", opts: []TokenOption{WithAudience("billing")}, wantErr: true},
+		{name: "audience list", signer: hmac, verifier: hmac, duration: time.Hour, issuer: "socle", opts: []TokenOption{WithAudience("billing", "api")}},
+		{name: "other secret", signer: NewJWTAuthenticator("another secret of thirty two byte", "api", "socle"), verifier: hmac, duration: time.Hour, issuer: "socle", wantErr: true},
+		{name: "forged kid", signer: forged, verifier: verifier, duration: time.Hour, issuer: "socle", wantErr: true},
+		{name: "algorithm confusion", signer: confused, verifier: verifier, duration: time.Hour, issuer: "socle", wantErr: true},
+		{name: "unknown kid", signer: NewJWTAuthenticatorWithKeys(NewKeySet(NewHMACKey("k9", []byte(testSecret))), "api", "socle"), verifier: verifier, duration: time.Hour, issuer: "socle", wantErr: true},
+		{
+			name: "claims", signer: hmac, verifier: hmac, duration: time.Hour, issuer: "socle",
+			opts: []TokenOption{
+				WithRoles("admin", "billing"),
+				WithScopes("read", "write"),
+				WithTenant("acme"),
+				WithClaim("org_id", 42),
+				// registered claims are not overridden
+				WithClaims(map[string]any{"sub": "mallory", "exp": 0, "plan": "pro"}),
+			},
+			check: func(t *testing.T, p *Payload) {
+				if p.Username != "ada" {
+					t.Errorf("Username = %q, want ada", p.Username)
+				}
+				if !slices.Equal(p.Roles, []string{"admin", "billing"}) || !p.HasRole("admin") {
+					t.Errorf("Roles = %v", p.Roles)
+				}
+				if !slices.Equal(p.Scopes, []string{"read", "write"}) || !p.HasScope("write") {
+					t.Errorf("Scopes = %v", p.Scopes)
+				}
+				if p.Tenant != "acme" {
+					t.Errorf("Tenant = %q", p.Tenant)
+				}
+				if id, ok := ClaimAs[int64](p, "org_id"); !ok || id != 42 {
+					t.Errorf("org_id = %v, %v", id, ok)
+				}
+				if p.ClaimString("plan") != "pro" || len(p.Claims) != 2 {
+					t.Errorf("Claims = %v, want org_id and plan", p.Claims)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, issued, err := tt.signer.GenerateToken("ada", tt.duration, tt.issuer, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := tt.verifier.ValidateToken(token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("token accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID != issued.ID || p.Username != "ada" || !p.ExpiredAt.Equal(issued.ExpiredAt.Truncate(time.Second)) {
+				t.Errorf("payload = %+v, want the one issued %+v", p, issued)
+			}
+			if tt.check != nil {
+				tt.check(t, p)
+			}
+		})
+	}
+}
+
+func TestValidateTokenWithoutKid(t *testing.T) {
+	// tokens signed before the key rotation carry no kid: they are verified by the current key
+	claims := jwt.MapClaims{
+		"sub": "ada", "iss": "socle", "aud": "api",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		"id": "7f0ad2ea-66f6-4e4d-8b8c-3f0f6f9f2f0e",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewJWTAuthenticator(testSecret, "api", "socle")
+	p, err := a.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID.String() != claims["id"] {
+		t.Errorf("ID = %s, want the id claim", p.ID)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	a := NewJWTAuthenticator(testSecret, "api", "socle")
+	a.Store = newTestStore(t)
+
+	refresh, _, err := a.GenerateRefreshToken(ctx, "ada", time.Hour, "socle", "", WithRoles("admin"), WithClaim("plan", "pro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ValidateToken(refresh); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh token used as access token: err = %v, want ErrInvalidToken", err)
+	}
+
+	pair, err := a.Refresh(ctx, refresh, time.Minute, time.Hour, "socle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := a.ValidateToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !access.HasRole("admin") || access.ClaimString("plan") != "pro" {
+		t.Errorf("access token claims = %+v, want those of the refresh token", access)
+	}
+	if _, err := a.ValidateRefreshToken(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token used as refresh token: err = %v, want ErrInvalidToken", err)
+	}
+
+	// the first refresh token leaked: its family is revoked
+	if _, err := a.Refresh(ctx, refresh, time.Minute, time.Hour, "socle"); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("reused refresh token: err = %v, want ErrTokenReused", err)
+	}
+	if _, err := a.Refresh(ctx, pair.RefreshToken, time.Minute, time.Hour, "socle"); err == nil {
+		t.Error("refresh token of a revoked family accepted")
+	}
+
+	if err := a.Revoke(ctx, pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("revoked access token: err = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestRefreshWithoutStore(t *testing.T) {
+	a := NewJWTAuthenticator(testSecret, "api", "socle")
+	refresh, _, err := a.GenerateRefreshToken(context.Background(), "ada", time.Hour, "socle", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(context.Background(), refresh, time.Minute, time.Hour, "socle"); !errors.Is(err, ErrNoTokenStore) {
+		t.Errorf("err = %v, want ErrNoTokenStore", err)
+	}
+}
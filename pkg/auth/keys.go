@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrNoSigningKey = errors.New("no private key to sign tokens")
+)
+
+// Key is a key signing or verifying tokens. HMAC keys have a Secret, the others a Public
+// key, and a Private one to sign.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Secret  []byte
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// NewHMACKey returns a HS256 key.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, Secret: secret}
+}
+
+// NewKey returns the key of a RSA (RS256), ECDSA (ES256, ES384 or ES512 after its curve)
+// or Ed25519 (EdDSA) private or public key.
+func NewKey(id string, key any) (*Key, error) {
+	k := &Key{ID: id}
+	if signer, ok := key.(crypto.Signer); ok {
+		k.Private = signer
+		key = signer.Public()
+	}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		k.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			k.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			k.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			k.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("key %s: unsupported curve", id)
+		}
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, key)
+	}
+	k.Public = key
+	return k, nil
+}
+
+// LoadKeyFile reads a PEM encoded private key (PKCS#8, PKCS#1 or SEC 1) or public key
+// (PKIX) from path.
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewKey(id, key)
+}
+
+// signingKey returns the key passed to the signing method.
+func (k *Key) signingKey() (any, error) {
+	if k.Secret != nil {
+		return k.Secret, nil
+	}
+	if k.Private == nil {
+		return nil, ErrNoSigningKey
+	}
+	return k.Private, nil
+}
+
+// verifyKey returns the key passed to the signing method to verify a signature.
+func (k *Key) verifyKey() any {
+	if k.Secret != nil {
+		return k.Secret
+	}
+	return k.Public
+}
+
+// KeyResolver finds the key verifying a token from the kid of its header.
+type KeyResolver interface {
+	Key(kid string) (*Key, error)
+}
+
+// KeySet holds the key signing the new tokens and the previous keys, which still verify
+// the tokens they signed until those expire. Rotating a key makes the current key a
+// previous one.
+type KeySet struct {
+	Current  *Key
+	Previous []*Key
+}
+
+// NewKeySet returns the key set signing with current.
+func NewKeySet(current *Key, previous ...*Key) *KeySet {
+	return &KeySet{Current: current, Previous: previous}
+}
+
+// Key returns the key identified by kid, or the current key when kid is empty.
+func (ks *KeySet) Key(kid string) (*Key, error) {
+	if kid == "" || kid == ks.Current.ID {
+		return ks.Current, nil
+	}
+	for _, k := range ks.Previous {
+		if k.ID == kid {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// Keys returns the current key, then the previous ones.
+func (ks *KeySet) Keys() []*Key {
+	return append([]*Key{ks.Current}, ks.Previous...)
+}
+
+// Resolvers is a KeyResolver trying each resolver in turn.
+type Resolvers []KeyResolver
+
+func (rs Resolvers) Key(kid string) (*Key, error) {
+	for _, r := range rs {
+		if k, err := r.Key(kid); err == nil {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// maxArgon2Memory caps the memory, in KiB, of the argon2id hashes CheckPassword checks,
+// so that a corrupted or planted hash cannot exhaust the memory of the process.
+const maxArgon2Memory = 1 << 20
+
+// Argon2Params are the argon2id parameters of HashPassword, after the recommendations of
+// RFC 9106 for memory constrained environments.
+var Argon2Params = struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen int
+	KeyLen  uint32
+}{Memory: 64 * 1024, Time: 3, Threads: 2, SaltLen: 16, KeyLen: 32}
+
+// HashPassword returns the argon2id hash of password, in the PHC string format
+// ($argon2id$v=19$m=65536,t=3,p=2$salt$hash).
+func HashPassword(password string) (string, error) {
+	p := Argon2Params
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// knownHash reports whether hash is in a format CheckPassword knows.
+func knownHash(hash string) bool {
+	return isBcrypt(hash) || strings.HasPrefix(hash, "$argon2id$")
+}
+
+// NeedsRehash reports whether hash is not an argon2id hash with the current Argon2Params,
+// so that the password should be hashed again with HashPassword after a successful login.
+func NeedsRehash(hash string) bool {
+	p := Argon2Params
+	prefix := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$", argon2.Version, p.Memory, p.Time, p.Threads)
+	return !strings.HasPrefix(hash, prefix)
+}
+
+// CheckPassword reports whether password matches hash, a bcrypt or argon2id hash.
+func CheckPassword(hash, password string) (bool, error) {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return checkArgon2(hash, password)
+	}
+	return false, ErrUnknownHash
+}
+
+func checkArgon2(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, ErrUnknownHash
+	}
+
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrUnknownHash
+	}
+	// argon2.IDKey panics on these, or allocates the memory asked for
+	if time == 0 || threads == 0 || memory > maxArgon2Memory {
+		return false, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return false, ErrUnknownHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false, ErrUnknownHash
+	}
+
+	got := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	argon, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcrypted := bcryptHash(t, "secret")
+	parts := strings.Split(argon, "$")
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		ok       bool
+		err      error
+	}{
+		{name: "argon2id", hash: argon, password: "secret", ok: true},
+		{name: "argon2id mismatch", hash: argon, password: "Secret"},
+		{name: "bcrypt", hash: bcrypted, password: "secret", ok: true},
+		{name: "bcrypt mismatch", hash: bcrypted, password: "Secret"},
+		{name: "plain text", hash: "secret", password: "secret", err: ErrUnknownHash},
+		{name: "empty", hash: "", password: "", err: ErrUnknownHash},
+		{name: "argon2i", hash: strings.Replace(argon, "$argon2id$", "$argon2i$", 1), password: "secret", err: ErrUnknownHash},
+		{name: "other version", hash: strings.Replace(argon, "$v=19$", "$v=16$", 1), password: "secret", err: ErrUnknownHash},
+		{name: "bad parameters", hash: strings.Replace(argon, parts[3], "m=x", 1), password: "secret", err: ErrUnknownHash},
+		{name: "bad salt", hash: strings.Replace(argon, parts[4], "!!", 1), password: "secret", err: ErrUnknownHash},
+		{name: "truncated", hash: strings.Join(parts[:5], "$"), password: "secret", err: ErrUnknownHash},
+		{name: "empty key", hash: strings.Join(parts[:5], "$") + "$", password: "secret", err: ErrUnknownHash},
+		{name: "empty salt", hash: strings.Replace(argon, "$"+parts[4]+"$", "$$", 1), password: "secret", err: ErrUnknownHash},
+		{name: "no passes", hash: strings.Replace(argon, "t=3", "t=0", 1), password: "secret", err: ErrUnknownHash},
+		{name: "no parallelism", hash: strings.Replace(argon, "p=2", "p=0", 1), password: "secret", err: ErrUnknownHash},
+		{name: "huge memory", hash: strings.Replace(argon, "m=65536", "m=4294967295", 1), password: "secret", err: ErrUnknownHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := CheckPassword(tt.hash, tt.password)
+			if ok != tt.ok || !errors.Is(err, tt.err) {
+				t.Errorf("CheckPassword = %v, %v, want %v, %v", ok, err, tt.ok, tt.err)
+			}
+		})
+	}
+}
+
+func TestHashPasswordSalted(t *testing.T) {
+	a, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("two hashes of the same password are equal")
+	}
+	if !strings.HasPrefix(a, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("hash = %s, want the PHC format", a)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcrypted := bcryptHash(t, "secret")
+
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+	}{
+		{name: "current argon2id", hash: argon},
+		{name: "weaker argon2id", hash: strings.Replace(argon, "t=3", "t=1", 1), rehash: true},
+		{name: "bcrypt", hash: bcrypted, rehash: true},
+		{name: "unknown", hash: "secret", rehash: true},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash); got != tt.rehash {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.rehash)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,12 +18,20 @@ var (
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Roles     []string  `json:"roles,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Audience  []string  `json:"audience,omitempty"`
+	Family    string    `json:"family,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+
+	// Claims holds the custom claims of the token, see ClaimAs.
+	Claims map[string]any `json:"claims,omitempty"`
 }
 
 // NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, duration time.Duration, opts ...TokenOption) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -34,9 +43,22 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
+	for _, opt := range opts {
+		opt(payload)
+	}
 	return payload, nil
 }
 
+// HasRole reports whether the token grants role.
+func (payload *Payload) HasRole(role string) bool {
+	return slices.Contains(payload.Roles, role)
+}
+
+// HasScope reports whether the token grants scope.
+func (payload *Payload) HasScope(scope string) bool {
+	return slices.Contains(payload.Scopes, scope)
+}
+
 // Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
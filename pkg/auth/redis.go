@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisStore stores the tokens in redis keys expiring with them.
+type RedisStore struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// NewRedisStore creates a store using the connections of pool, with keys prefixed by prefix.
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{Pool: pool, Prefix: prefix + "socle:tokens:"}
+}
+
+// consumeScript marks a refresh token used, unless it is missing, revoked or already used.
+var consumeScript = redis.NewScript(1, `
+local t = redis.call("HGETALL", KEYS[1])
+if #t == 0 then return {"missing"} end
+local f = {}
+for i = 1, #t, 2 do f[t[i]] = t[i + 1] end
+if f.revoked == "1" then return {"revoked", f.family, f.username, f.expires} end
+if f.used == "1" then return {"used", f.family, f.username, f.expires} end
+redis.call("HSET", KEYS[1], "used", "1")
+return {"ok", f.family, f.username, f.expires}
+`)
+
+func (s *RedisStore) refreshKey(id string) string { return s.Prefix + "refresh:" + id }
+func (s *RedisStore) familyKey(f string) string   { return s.Prefix + "family:" + f }
+func (s *RedisStore) userKey(u string) string     { return s.Prefix + "user:" + u }
+func (s *RedisStore) revokedKey(id string) string { return s.Prefix + "revoked:" + id }
+func (s *RedisStore) cutoffKey(u string) string   { return s.Prefix + "cutoff:" + u }
+
+func (s *RedisStore) SaveRefresh(ctx context.Context, t RefreshToken) error {
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	expires := t.ExpiresAt.Unix()
+	_ = conn.Send("MULTI")
+	_ = conn.Send("HSET", s.refreshKey(t.ID), "family", t.Family, "username", t.Username,
+		"expires", expires, "used", boolFlag(t.Used), "revoked", boolFlag(t.Revoked))
+	_ = conn.Send("EXPIREAT", s.refreshKey(t.ID), expires)
+	// the family and user sets live as long as their last token
+	for _, key := range []string{s.familyKey(t.Family), s.userKey(t.Username)} {
+		_ = conn.Send("SADD", key, t.ID)
+		_ = conn.Send("EXPIREAT", key, expires)
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) ConsumeRefresh(ctx context.Context, id string) (RefreshToken, error) {
+	t := RefreshToken{ID: id}
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return t, err
+	}
+	defer conn.Close()
+
+	values, err := redis.Strings(consumeScript.Do(conn, s.refreshKey(id)))
+	if err != nil {
+		return t, err
+	}
+	if values[0] == "missing" {
+		return t, ErrTokenNotFound
+	}
+
+	t.Family, t.Username = values[1], values[2]
+	if expires, err := redis.Int64(values[3], nil); err == nil {
+		t.ExpiresAt = time.Unix(expires, 0)
+	}
+	switch values[0] {
+	case "revoked":
+		t.Revoked = true
+		return t, ErrTokenRevoked
+	case "used":
+		t.Used = true
+		return t, ErrTokenReused
+	}
+	return t, nil
+}
+
+// revokeSet revokes the refresh tokens listed in the set key.
+func (s *RedisStore) revokeSet(conn redis.Conn, key string) error {
+	ids, err := redis.Strings(conn.Do("SMEMBERS", key))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		// the expired tokens are gone, do not recreate them
+		if exists, _ := redis.Bool(conn.Do("EXISTS", s.refreshKey(id))); exists {
+			if _, err := conn.Do("HSET", s.refreshKey(id), "revoked", "1"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) RevokeFamily(ctx context.Context, family string) error {
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return s.revokeSet(conn, s.familyKey(family))
+}
+
+func (s *RedisStore) Revoke(ctx context.Context, jti string, expires time.Time) error {
+	if !expires.After(time.Now()) {
+		return nil
+	}
+
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("SET", s.revokedKey(jti), "1", "EXAT", expires.Unix())
+	return err
+}
+
+func (s *RedisStore) RevokeUser(ctx context.Context, username string, at, expires time.Time) error {
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := s.revokeSet(conn, s.userKey(username)); err != nil {
+		return err
+	}
+	if !expires.After(time.Now()) {
+		return nil
+	}
+	_, err = conn.Do("SET", s.cutoffKey(username), at.UnixMilli(), "EXAT", expires.Unix())
+	return err
+}
+
+func (s *RedisStore) Revoked(ctx context.Context, jti, username string, issuedAt time.Time) (bool, error) {
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("MGET", s.revokedKey(jti), s.cutoffKey(username)))
+	if err != nil {
+		return false, err
+	}
+	if values[0] != nil {
+		return true, nil
+	}
+
+	cutoff, err := redis.Int64(values[1], nil)
+	if errors.Is(err, redis.ErrNil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revokedBefore(issuedAt, time.UnixMilli(cutoff)), nil
+}
+
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrNoTokenStore = errors.New("no token store")
+
+// refreshType is the typ claim of the refresh tokens.
+const refreshType = "refresh"
+
+// TokenPair is the response of a token refresh.
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// GenerateRefreshToken returns a refresh token of username in family, or in a new family
+// when family is empty, and records it in the Store. The claims of opts are carried over
+// to the access tokens it is exchanged for.
+func (a *JWTAuthenticator) GenerateRefreshToken(ctx context.Context, username string, duration time.Duration, issuer, family string, opts ...TokenOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration, opts...)
+	if err != nil {
+		return "", payload, err
+	}
+	if family == "" {
+		family = payload.ID.String()
+	}
+	payload.Family = family
+
+	tokenString, err := a.sign(payload, issuer, jwt.MapClaims{"typ": refreshType, "fam": family})
+	if err != nil {
+		return "", payload, err
+	}
+
+	if a.Store != nil {
+		err = a.Store.SaveRefresh(ctx, RefreshToken{
+			ID:        payload.ID.String(),
+			Family:    family,
+			Username:  username,
+			ExpiresAt: payload.ExpiredAt,
+		})
+		if err != nil {
+			return "", payload, err
+		}
+	}
+	return tokenString, payload, nil
+}
+
+// ValidateRefreshToken validates a refresh token, without consuming it.
+func (a *JWTAuthenticator) ValidateRefreshToken(token string) (*Payload, error) {
+	payload, claims, err := a.parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims["typ"] != refreshType || payload.Family == "" {
+		return nil, ErrInvalidToken
+	}
+	return payload, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token of
+// the same family. A refresh token is used once: presenting it again means it leaked, so
+// its whole family is revoked and ErrTokenReused returned.
+func (a *JWTAuthenticator) Refresh(ctx context.Context, token string, duration, refreshDuration time.Duration, issuer string) (*TokenPair, error) {
+	if a.Store == nil {
+		return nil, ErrNoTokenStore
+	}
+
+	payload, err := a.ValidateRefreshToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := a.Store.ConsumeRefresh(ctx, payload.ID.String())
+	switch {
+	case errors.Is(err, ErrTokenReused):
+		if err := a.Store.RevokeFamily(ctx, payload.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	case errors.Is(err, ErrTokenNotFound):
+		return nil, ErrInvalidToken
+	case err != nil:
+		return nil, err
+	}
+
+	opts := payload.options()
+	accessToken, access, err := a.GenerateToken(t.Username, duration, issuer, opts...)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, _, err := a.GenerateRefreshToken(ctx, t.Username, refreshDuration, issuer, t.Family, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    access.ExpiredAt,
+	}, nil
+}
+
+// Revoke revokes token: the family of a refresh token, or an access token until it
+// expires. It returns ErrInvalidToken when token cannot be verified.
+func (a *JWTAuthenticator) Revoke(ctx context.Context, token string) error {
+	if a.Store == nil {
+		return ErrNoTokenStore
+	}
+
+	payload, claims, err := a.parse(token)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if claims["typ"] == refreshType {
+		return a.Store.RevokeFamily(ctx, payload.Family)
+	}
+	return a.Store.Revoke(ctx, payload.ID.String(), payload.ExpiredAt)
+}
+
+// RevokeUser revokes all the tokens issued to username so far, logging out all its
+// sessions. duration is the lifetime of the longest lived tokens.
+func (a *JWTAuthenticator) RevokeUser(ctx context.Context, username string, duration time.Duration) error {
+	if a.Store == nil {
+		return ErrNoTokenStore
+	}
+	now := time.Now()
+	return a.Store.RevokeUser(ctx, username, now, now.Add(duration))
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+)
+
+// secretEncoding encodes the secret tokens, readable in URLs and cookies.
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecretToken returns a random token for remember-me cookies, password resets or api
+// tokens, and its hash. Only the hash is stored, so that a leak of the database does
+// not give the tokens away.
+func NewSecretToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := secretEncoding.EncodeToString(b)
+	return token, HashSecretToken(token), nil
+}
+
+// HashSecretToken returns the hash of token, to look it up.
+func HashSecretToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// CheckSecretToken reports in constant time whether token has hash.
+func CheckSecretToken(token string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(HashSecretToken(token), hash) == 1
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+)
+
+func TestSecretToken(t *testing.T) {
+	token, hash, err := NewSecretToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, otherHash, err := NewSecretToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == other || bytes.Equal(hash, otherHash) {
+		t.Fatal("two tokens are equal")
+	}
+	if len(token) != 52 || url.QueryEscape(token) != token {
+		t.Errorf("token = %q, want 52 characters safe in URLs", token)
+	}
+	if !bytes.Equal(hash, HashSecretToken(token)) {
+		t.Error("the hash returned with the token is not its HashSecretToken")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		hash  []byte
+		ok    bool
+	}{
+		{name: "token", token: token, hash: hash, ok: true},
+		{name: "other token", token: other, hash: hash},
+		{name: "hash as token", token: string(hash), hash: hash},
+		{name: "truncated hash", token: token, hash: hash[:16]},
+		{name: "empty", token: "", hash: nil},
+	}
+	for _, tt := range tests {
+		if got := CheckSecretToken(tt.token, tt.hash); got != tt.ok {
+			t.Errorf("%s: CheckSecretToken = %v, want %v", tt.name, got, tt.ok)
+		}
+	}
+}
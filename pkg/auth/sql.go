@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/socle-framework/socle/internal/sqldialect"
+)
+
+// SQLStore stores the tokens in the tables created by `socle make tokens`. The revoked_at
+// column of the revoked users holds Unix milliseconds, DATETIME dropping the fraction of
+// second on mysql.
+type SQLStore struct {
+	DB           *sql.DB
+	DBType       string
+	RefreshTable string
+	RevokedTable string
+	UsersTable   string
+}
+
+// NewSQLStore creates a store using the socle_refresh_tokens, socle_revoked_tokens and
+// socle_revoked_users tables of db.
+func NewSQLStore(db *sql.DB, dbType string) *SQLStore {
+	return &SQLStore{
+		DB:           db,
+		DBType:       dbType,
+		RefreshTable: "socle_refresh_tokens",
+		RevokedTable: "socle_revoked_tokens",
+		UsersTable:   "socle_revoked_users",
+	}
+}
+
+// rebind replaces the ? placeholders of query by $n ones for postgres.
+func (s *SQLStore) rebind(query string) string {
+	return sqldialect.Rebind(s.DBType, query)
+}
+
+func (s *SQLStore) SaveRefresh(ctx context.Context, t RefreshToken) error {
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %s (id, family, username, expires_at, used, revoked, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, s.RefreshTable))
+	_, err := s.DB.ExecContext(ctx, query, t.ID, t.Family, t.Username, t.ExpiresAt, t.Used, t.Revoked, now, now)
+	return err
+}
+
+func (s *SQLStore) ConsumeRefresh(ctx context.Context, id string) (RefreshToken, error) {
+	var t RefreshToken
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return t, err
+	}
+	defer tx.Rollback()
+
+	lock := " FOR UPDATE"
+	if sqldialect.SQLite(s.DBType) {
+		lock = ""
+	}
+	query := s.rebind(fmt.Sprintf(`SELECT id, family, username, expires_at, used, revoked FROM %s WHERE id = ?%s`, s.RefreshTable, lock))
+	err = tx.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.Family, &t.Username, &t.ExpiresAt, &t.Used, &t.Revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrTokenNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+	if t.Revoked {
+		return t, ErrTokenRevoked
+	}
+	if t.Used {
+		return t, ErrTokenReused
+	}
+
+	query = s.rebind(fmt.Sprintf(`UPDATE %s SET used = ?, updated_at = ? WHERE id = ?`, s.RefreshTable))
+	if _, err := tx.ExecContext(ctx, query, true, time.Now(), id); err != nil {
+		return t, err
+	}
+	return t, tx.Commit()
+}
+
+func (s *SQLStore) RevokeFamily(ctx context.Context, family string) error {
+	query := s.rebind(fmt.Sprintf(`UPDATE %s SET revoked = ?, updated_at = ? WHERE family = ?`, s.RefreshTable))
+	_, err := s.DB.ExecContext(ctx, query, true, time.Now(), family)
+	return err
+}
+
+func (s *SQLStore) Revoke(ctx context.Context, jti string, expires time.Time) error {
+	// a token revoked twice at once stays revoked, without a duplicate key error
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %s (id, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?) %s`,
+		s.RevokedTable, sqldialect.Upsert(s.DBType, "id")))
+	_, err := s.DB.ExecContext(ctx, query, jti, expires, now, now)
+	return err
+}
+
+func (s *SQLStore) RevokeUser(ctx context.Context, username string, at, expires time.Time) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf(`UPDATE %s SET revoked = ?, updated_at = ? WHERE username = ? AND revoked = ?`, s.RefreshTable))
+	if _, err := tx.ExecContext(ctx, query, true, now, username, false); err != nil {
+		return err
+	}
+
+	query = s.rebind(fmt.Sprintf(`INSERT INTO %s (username, revoked_at, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?) %s`,
+		s.UsersTable, sqldialect.Upsert(s.DBType, "username", "revoked_at", "expires_at", "updated_at")))
+	if _, err := tx.ExecContext(ctx, query, username, at.UnixMilli(), expires, now, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) revoked(ctx context.Context, jti string) (bool, error) {
+	var n int
+	query := s.rebind(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id = ?`, s.RevokedTable))
+	err := s.DB.QueryRowContext(ctx, query, jti).Scan(&n)
+	return n > 0, err
+}
+
+func (s *SQLStore) Revoked(ctx context.Context, jti, username string, issuedAt time.Time) (bool, error) {
+	revoked, err := s.revoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	var cutoff int64
+	query := s.rebind(fmt.Sprintf(`SELECT revoked_at FROM %s WHERE username = ? AND expires_at > ?`, s.UsersTable))
+	err = s.DB.QueryRowContext(ctx, query, username, time.Now()).Scan(&cutoff)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revokedBefore(issuedAt, time.UnixMilli(cutoff)), nil
+}
+
+// Prune deletes the expired records. Schedule it, the other stores expire them themselves.
+func (s *SQLStore) Prune(ctx context.Context) error {
+	now := time.Now()
+	for _, table := range []string{s.RefreshTable, s.RevokedTable, s.UsersTable} {
+		query := s.rebind(fmt.Sprintf(`DELETE FROM %s WHERE expires_at < ?`, table))
+		if _, err := s.DB.ExecContext(ctx, query, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrTokenReused   = errors.New("refresh token already used")
+	ErrTokenRevoked  = errors.New("token has been revoked")
+)
+
+// RefreshToken is the record of an issued refresh token. The tokens rotated from the
+// same login share their Family, which is revoked as a whole when one of them is reused.
+type RefreshToken struct {
+	ID        string
+	Family    string
+	Username  string
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+// TokenStore persists the refresh tokens and the revoked tokens.
+type TokenStore interface {
+	// SaveRefresh records a new refresh token.
+	SaveRefresh(ctx context.Context, t RefreshToken) error
+	// ConsumeRefresh marks the refresh token id as used and returns it. It fails with
+	// ErrTokenReused when the token was already used, and ErrTokenRevoked when revoked.
+	ConsumeRefresh(ctx context.Context, id string) (RefreshToken, error)
+	// RevokeFamily revokes the refresh tokens of family.
+	RevokeFamily(ctx context.Context, family string) error
+	// Revoke revokes the token jti until it expires.
+	Revoke(ctx context.Context, jti string, expires time.Time) error
+	// RevokeUser revokes the refresh tokens of username, and the tokens issued to the
+	// user before at, until expires.
+	RevokeUser(ctx context.Context, username string, at, expires time.Time) error
+	// Revoked reports whether the token jti, issued to username at issuedAt, is revoked.
+	Revoked(ctx context.Context, jti, username string, issuedAt time.Time) (bool, error)
+}
+
+// revokedBefore reports whether a token issued at issuedAt, with the millisecond precision
+// of the iat claim, was issued before the revocation of its user at cutoff. The tokens
+// issued in the same millisecond are revoked.
+func revokedBefore(issuedAt, cutoff time.Time) bool {
+	return !cutoff.IsZero() && !issuedAt.After(cutoff.Truncate(time.Millisecond))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+func TestRevokedBefore(t *testing.T) {
+	cutoff := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		cutoff   time.Time
+		revoked  bool
+	}{
+		{"no cutoff", cutoff, time.Time{}, false},
+		{"previous second", cutoff.Add(-time.Second), cutoff, true},
+		{"same second, before", cutoff.Add(-300 * time.Millisecond), cutoff, true},
+		{"same millisecond", cutoff, cutoff.Add(400 * time.Microsecond), true},
+		{"same second, after", cutoff.Add(300 * time.Millisecond), cutoff, false},
+		{"next second", cutoff.Add(time.Second), cutoff, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revokedBefore(tt.issuedAt, tt.cutoff); got != tt.revoked {
+				t.Errorf("revokedBefore = %v, want %v", got, tt.revoked)
+			}
+		})
+	}
+}
+
+func newTestStore(t *testing.T) *BadgerStore {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewBadgerStore(db)
+}
+
+func TestRevokeUserSameSecond(t *testing.T) {
+	ctx := context.Background()
+	a := NewJWTAuthenticator("0123456789abcdef0123456789abcdef", "api", "socle")
+	a.Store = newTestStore(t)
+
+	before, _, err := a.GenerateToken("ada", time.Hour, "socle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := a.RevokeUser(ctx, "ada", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	after, _, err := a.GenerateToken("ada", time.Hour, "socle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateToken(before); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token issued before the logout: err = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := a.ValidateToken(after); err != nil {
+		t.Errorf("token issued after the logout: err = %v", err)
+	}
+}
+
+func TestIssuedAtPrecision(t *testing.T) {
+	a := NewJWTAuthenticator("0123456789abcdef0123456789abcdef", "api", "socle")
+	token, payload, err := a.GenerateToken("ada", time.Hour, "socle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := a.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := payload.IssuedAt.Truncate(time.Millisecond); !parsed.IssuedAt.Equal(want) {
+		t.Errorf("IssuedAt = %v, want %v", parsed.IssuedAt, want)
+	}
+}
@@ -0,0 +1,192 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader serves a certificate and, optionally, a pool of client CAs read from files,
+// and reloads them when the files change, without restarting the server.
+type Reloader struct {
+	CertFile string
+	KeyFile  string
+	// CAFile holds the CAs verifying the client certificates, empty when they are not verified.
+	CAFile   string
+	ErrorLog *log.Logger
+	InfoLog  *log.Logger
+
+	cert    atomic.Pointer[tls.Certificate]
+	clients atomic.Pointer[x509.CertPool]
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// NewReloader creates a reloader and loads its files.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+		ErrorLog: log.New(os.Stdout, "certs ERROR\t", log.Ldate|log.Ltime),
+		InfoLog:  log.New(os.Stdout, "certs INFO\t", log.Ldate|log.Ltime),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. On error, the certificates loaded before are kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.CAFile != "" {
+		caBytes, err := os.ReadFile(r.CAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return errors.New("CA cert not valid")
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.clients.Store(pool)
+	r.modTime = modTime
+	return nil
+}
+
+// lastModified returns the most recent modification time of the files.
+func (r *Reloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.CertFile, r.KeyFile, r.CAFile} {
+		if f == "" {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// changed reports whether a file was modified since the last reload.
+func (r *Reloader) changed() bool {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return modTime.After(r.modTime)
+}
+
+// Watch checks the files every interval and reloads them when they change, until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				r.ErrorLog.Println("unable to reload certificates:", err)
+				continue
+			}
+			r.InfoLog.Println("Certificates reloaded from", r.CertFile)
+		}
+	}
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// ClientCAs returns the current pool of client CAs.
+func (r *Reloader) ClientCAs() *x509.CertPool {
+	return r.clients.Load()
+}
+
+// TLSConfig returns a configuration based on base that picks the current certificate and
+// client CAs at every handshake. The client certificates are verified against the current
+// CAs by VerifyConnection rather than by a configuration per client, which would replace
+// the one completed by the server, with the ALPN protocols it adds such as h2.
+func (r *Reloader) TLSConfig(base *tls.Config) *tls.Config {
+	cfg := base.Clone()
+	cfg.Certificates = nil
+	cfg.GetCertificate = r.GetCertificate
+	if r.CAFile == "" {
+		return cfg
+	}
+
+	switch base.ClientAuth {
+	case tls.VerifyClientCertIfGiven:
+		cfg.ClientAuth = tls.RequestClientCert
+	case tls.RequireAndVerifyClientCert:
+		cfg.ClientAuth = tls.RequireAnyClientCert
+	default:
+		return cfg
+	}
+	// no CA names in the certificate request: those of a reloaded pool would be stale, and
+	// the clients would keep their certificate of the new CA to themselves
+	cfg.ClientCAs = nil
+
+	verify := base.VerifyConnection
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) > 0 {
+			if err := r.verifyClient(cs.PeerCertificates); err != nil {
+				return err
+			}
+		}
+		if verify != nil {
+			return verify(cs)
+		}
+		return nil
+	}
+	return cfg
+}
+
+// verifyClient verifies the chain of a client certificate against the current client CAs.
+func (r *Reloader) verifyClient(chain []*x509.Certificate) error {
+	opts := x509.VerifyOptions{
+		Roots:         r.ClientCAs(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range chain[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(opts)
+	return err
+}
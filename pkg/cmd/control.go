@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/socle-framework/socle/pkg/control"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(reloadLogCmd)
+	rootCmd.AddCommand(drainCmd)
+	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheFlushCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the running server",
+	Run: func(cmd *cobra.Command, args []string) {
+		var st control.Status
+		callControl("RPCServer.Status", control.Empty{}, &st)
+
+		color.Yellow("Version:      %s", st.Version)
+		color.Yellow("Entries:      %v", st.Entries)
+		color.Yellow("Uptime:       %s", time.Since(st.StartedAt).Round(time.Second))
+		color.Yellow("Debug:        %v", st.Debug)
+		color.Yellow("Maintenance:  %v", st.Maintenance)
+		color.Yellow("Goroutines:   %d", st.Goroutines)
+		color.Yellow("DB conns:     %d", st.DBOpenConns)
+	},
+}
+
+var reloadLogCmd = &cobra.Command{
+	Use:   "reload-log",
+	Short: "Reload DEBUG and LOG_LEVEL from .env in the running server",
+	Long: `Reload DEBUG and LOG_LEVEL from .env in the running server.
+
+Only the logging is reloaded. The other settings of .env and socle.yaml configure the
+servers, connections and middlewares when the server starts: restart it to apply them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var result string
+		callControl("RPCServer.ReloadLogging", control.Empty{}, &result)
+		color.Yellow(result)
+	},
+}
+
+var drainCmd = &cobra.Command{
+	Use:   "drain",
+	Short: "Drain connections and stop the running server gracefully",
+	Run: func(cmd *cobra.Command, args []string) {
+		var result string
+		callControl("RPCServer.Drain", control.Empty{}, &result)
+		color.Yellow(result)
+	},
+}
+
+var debugCmd = &cobra.Command{
+	Use:       "debug [on|off]",
+	Short:     "Toggle debug logging in the running server",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] != "on" && args[0] != "off" {
+			exitGracefully(errors.New("expected on or off"))
+		}
+		var result string
+		callControl("RPCServer.SetDebug", args[0] == "on", &result)
+		color.Yellow(result)
+	},
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of the running server",
+}
+
+var cacheFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Empty the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		var result string
+		callControl("RPCServer.FlushCache", control.Empty{}, &result)
+		color.Yellow(result)
+	},
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage the scheduled jobs of the running server",
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scheduled jobs",
+	Run: func(cmd *cobra.Command, args []string) {
+		var jobs []control.ScheduledJob
+		callControl("RPCServer.ScheduledJobs", control.Empty{}, &jobs)
+
+		for _, job := range jobs {
+			prev := "never"
+			if !job.Prev.IsZero() {
+				prev = job.Prev.Format(time.RFC3339)
+			}
+			color.Yellow("%4d  %-20s next: %s  prev: %s", job.ID, job.Name, job.Next.Format(time.RFC3339), prev)
+		}
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run <id>",
+	Short: "Run a scheduled job now",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			exitGracefully(err)
+		}
+		var result string
+		callControl("RPCServer.TriggerJob", id, &result)
+		color.Yellow(result)
+	},
+}
+
+// callControl calls method on the control plane of the running server and exits on error.
+func callControl(method string, args any, reply any) {
+	c, err := controlClient()
+	if err != nil {
+		exitGracefully(err)
+	}
+	defer c.Close()
+
+	err = c.Call(method, args, reply)
+	if err != nil {
+		exitGracefully(err)
+	}
+}
+
+// controlClient connects to the control plane with the settings of .env: the unix socket
+// RPC_SOCKET, or RPC_ADDRESS:RPC_PORT over TLS with RPC_TLS_CERT.
+func controlClient() (*rpc.Client, error) {
+	key := []byte(os.Getenv("KEY"))
+	if len(key) == 0 {
+		return nil, errors.New("KEY is not set in .env")
+	}
+
+	if socket := os.Getenv("RPC_SOCKET"); socket != "" {
+		if !filepath.IsAbs(socket) {
+			socket = filepath.Join(s.RootPath, socket)
+		}
+		return control.Dial("unix", socket, key, nil)
+	}
+
+	port := os.Getenv("RPC_PORT")
+	if port == "" {
+		return nil, errors.New("neither RPC_SOCKET nor RPC_PORT is set in .env")
+	}
+	address := os.Getenv("RPC_ADDRESS")
+	if address == "" {
+		address = "127.0.0.1"
+	}
+
+	cert := os.Getenv("RPC_TLS_CERT")
+	if cert == "" {
+		return nil, errors.New("RPC_PORT requires RPC_TLS_CERT, or use RPC_SOCKET")
+	}
+	ca := os.Getenv("RPC_TLS_CA")
+	if ca == "" {
+		ca = cert
+	}
+	caBytes, err := os.ReadFile(ca)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("no certificate found in %s", ca)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    roots,
+		ServerName: address,
+		MinVersion: tls.VersionTLS13,
+	}
+
+	return control.Dial("tcp", net.JoinHostPort(address, port), key, tlsConfig)
+}
@@ -54,17 +54,29 @@ func showHelp() {
 	color.Yellow(`Available commands:
 
 	help                           - show the help commands
-	down                           - put the server into maintenance mode
+	down                           - put the server into maintenance mode (--message, --until, --allow, --secret)
 	up                             - take the server out of maintenance mode
+	status                         - show the status of the running server
+	reload-log                     - reload DEBUG and LOG_LEVEL from .env in the running server
+	drain                          - drain connections and stop the running server gracefully
+	debug <on|off>                 - toggle debug logging in the running server
+	cache flush                    - empty the cache of the running server
+	schedule list                  - list the scheduled jobs of the running server
+	schedule run <id>              - run a scheduled job now
 	version                        - print application version
 	migrate                        - runs all up migrations that have not been run previously
 	migrate down                   - reverses the most recent migration
 	migrate reset                  - runs all down migrations in reverse order, and then all up migrations
 	make migration <name> <format> - creates two new up and down migrations in the migrations folder; format=sql/fizz (default fizz)
-	make auth                      - creates and runs migrations for authentication tables, and creates models and middleware
+	make auth [fizz|sql]           - creates and runs migrations for authentication tables, and creates models, middleware, handlers and views
 	make handler <name>            - creates a stub handler in the handlers directory
 	make model <name>              - creates a new model in the data directory
 	make session                   - creates a table in the database as a session store
+	make jobs                      - creates the migrations of the table used by the sql job store
+	make maintenance               - creates the migrations of the table used by the sql maintenance store
+	make tokens                    - creates the migrations of the tables used by the sql token store
+	make oauth                     - creates the migrations of the table linking the oauth identities to the users (after make auth)
+	make certs                     - creates a CA, a server and a client certificate for the self TLS strategy (--hosts, --days, --force)
 	make mail <name>               - creates two starter mail templates in the mail directory
 	
 	`)
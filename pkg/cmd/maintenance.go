@@ -2,14 +2,26 @@ package cmd
 
 import (
 	"fmt"
-	"net/rpc"
-	"os"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/socle-framework/socle/pkg/maintenance"
 	"github.com/spf13/cobra"
 )
 
+var (
+	maintenanceMessage string
+	maintenanceUntil   string
+	maintenanceAllow   []string
+	maintenanceSecret  string
+)
+
 func init() {
+	EnableMaintenanceModeCmd.Flags().StringVar(&maintenanceMessage, "message", "", "message shown to the clients")
+	EnableMaintenanceModeCmd.Flags().StringVar(&maintenanceUntil, "until", "", "expected end, as a duration (2h) or a RFC3339 time")
+	EnableMaintenanceModeCmd.Flags().StringSliceVar(&maintenanceAllow, "allow", nil, "IP address or CIDR range still served, can be repeated (read from the proxy headers with the real_ip middleware)")
+	EnableMaintenanceModeCmd.Flags().StringVar(&maintenanceSecret, "secret", "", "visiting /<secret> lets a browser bypass the maintenance")
+
 	rootCmd.AddCommand(EnableMaintenanceModeCmd)
 	rootCmd.AddCommand(DisableMaintenanceModeCmd)
 }
@@ -19,7 +31,18 @@ var EnableMaintenanceModeCmd = &cobra.Command{
 	Short: "Enable maintenance mode",
 	Args:  cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		inMaintenanceMode(true)
+		until, err := parseUntil(maintenanceUntil)
+		if err != nil {
+			exitGracefully(err)
+		}
+
+		inMaintenanceMode(maintenance.State{
+			Enabled: true,
+			Message: maintenanceMessage,
+			Until:   until,
+			Allow:   maintenanceAllow,
+			Secret:  maintenanceSecret,
+		})
 	},
 }
 
@@ -28,23 +51,27 @@ var DisableMaintenanceModeCmd = &cobra.Command{
 	Short: "Disable maintenance mode",
 	Args:  cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		inMaintenanceMode(false)
+		inMaintenanceMode(maintenance.State{})
 	},
 }
 
-func inMaintenanceMode(mode bool) {
-	rpcPort := os.Getenv("RPC_PORT")
-	c, err := rpc.Dial("tcp", "127.0.0.1:"+rpcPort)
-	if err != nil {
-		exitGracefully(err)
+// parseUntil reads the expected end of the maintenance, either a duration from now or a RFC3339 time.
+func parseUntil(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
-
-	fmt.Println("Connected...")
-	var result string
-	err = c.Call("RPCServer.MaintenanceMode", mode, &result)
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(d), nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		exitGracefully(err)
+		return time.Time{}, fmt.Errorf("invalid --until %q: expected a duration or a RFC3339 time", value)
 	}
+	return until, nil
+}
 
+func inMaintenanceMode(st maintenance.State) {
+	var result string
+	callControl("RPCServer.MaintenanceMode", st, &result)
 	color.Yellow(result)
 }
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	makeCmd.AddCommand(authCmd)
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth [fizz|sql]",
+	Short: "creates and runs migrations for authentication tables, and creates models and middleware",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := "fizz"
+		if len(args) > 0 {
+			format = strings.ToLower(args[0])
+		}
+		doAuth(format)
+	},
+}
+
+// authFiles are the files generated by make auth, from templates/auth to the application.
+var authFiles = map[string]string{
+	"data/auth.go.txt":               "data/auth.go",
+	"data/user.go.txt":               "data/user.go",
+	"data/token.go.txt":              "data/token.go",
+	"middleware/auth.go.txt":         "middleware/auth.go",
+	"handlers/auth-handlers.go.txt":  "handlers/auth-handlers.go",
+	"views/login.jet":                "views/login.jet",
+	"views/forgot.jet":               "views/forgot.jet",
+	"views/reset-password.jet":       "views/reset-password.jet",
+	"mail/password-reset.html.tmpl":  "mail/password-reset.html.tmpl",
+	"mail/password-reset.plain.tmpl": "mail/password-reset.plain.tmpl",
+}
+
+func doAuth(format string) error {
+	checkForDB()
+
+	var up, down []byte
+	var err error
+	switch format {
+	case "fizz":
+		up, down, err = readTemplates("templates/auth/auth_up.fizz", "templates/auth/auth_down.fizz")
+	case "sql":
+		var dialect string
+		switch s.DB.DBType {
+		case "postgres", "postgresql", "pgx":
+			dialect = "postgres"
+		case "mysql", "mariadb":
+			dialect = "mysql"
+		default:
+			exitGracefully(errors.New("no sql migrations for the database type " + s.DB.DBType + ", use fizz"))
+		}
+		up, down, err = readTemplates("templates/auth/auth."+dialect+".up.sql", "templates/auth/auth."+dialect+".down.sql")
+	default:
+		exitGracefully(errors.New("the format must be fizz or sql"))
+	}
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = s.CreatePopMigration(up, down, "auth_tables", format)
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = doMigrate("up", "")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	appName, err := moduleName(s.RootPath + "/go.mod")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	for from, to := range authFiles {
+		target := filepath.Join(s.RootPath, to)
+		if err := s.CreateDirIfNotExist(filepath.Dir(target)); err != nil {
+			exitGracefully(err)
+		}
+		if fileExists(target) {
+			color.Yellow("%s already exists, skipped", to)
+			continue
+		}
+
+		data, err := templateFS.ReadFile("templates/auth/" + from)
+		if err != nil {
+			exitGracefully(err)
+		}
+		data = []byte(strings.ReplaceAll(string(data), "${APP_NAME}", appName))
+		if err := copyDataToFile(data, target); err != nil {
+			exitGracefully(err)
+		}
+	}
+
+	color.Yellow("  - users, tokens, remember_tokens and password_resets tables created and migrated")
+	color.Yellow("  - models created in data, middleware in middleware, handlers in handlers")
+	color.Yellow("")
+	color.Yellow("Create the models with data.NewAuthModels(app.DB.Pool, app.DB.DBType), and mount the")
+	color.Yellow("routes listed in handlers/auth-handlers.go. Protect the web routes with the Remember")
+	color.Yellow("then Auth middleware, after the session middleware.")
+
+	return nil
+}
+
+func readTemplates(upPath, downPath string) ([]byte, []byte, error) {
+	up, err := templateFS.ReadFile(upPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	down, err := templateFS.ReadFile(downPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return up, down, nil
+}
+
+// moduleName returns the module path declared in the go.mod file at path.
+func moduleName(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); found {
+			return strings.Trim(strings.TrimSpace(name), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New(path + ": no module declaration")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/socle-framework/socle"
+	"github.com/socle-framework/socle/pkg/certs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	certHosts []string
+	certDays  int
+	certForce bool
+)
+
+func init() {
+	certsCmd.Flags().StringSliceVar(&certHosts, "hosts", nil, "DNS names and IP addresses of the server certificate (default localhost, 127.0.0.1, ::1 and SERVER_NAME)")
+	certsCmd.Flags().IntVar(&certDays, "days", 365, "validity of the certificates, in days")
+	certsCmd.Flags().BoolVar(&certForce, "force", false, "create a new CA even if one already exists")
+	makeCmd.AddCommand(certsCmd)
+}
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "creates a CA, a server and a client certificate for the self TLS strategy",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := doCerts(); err != nil {
+			exitGracefully(err)
+		}
+	},
+}
+
+func doCerts() error {
+	cfg, err := socle.LoadAppConfig(s.RootPath)
+	if err != nil {
+		return err
+	}
+
+	var caNames, serverNames, clientNames []string
+	for _, sec := range []struct{ ca, server, client string }{
+		{cfg.Entries.Web.Security.TLS.CACertName, cfg.Entries.Web.Security.TLS.ServerCertName, cfg.Entries.Web.Security.TLS.ClientCertName},
+		{cfg.Entries.Api.Security.TLS.CACertName, cfg.Entries.Api.Security.TLS.ServerCertName, cfg.Entries.Api.Security.TLS.ClientCertName},
+	} {
+		caNames = appendUnique(caNames, sec.ca)
+		serverNames = appendUnique(serverNames, sec.server)
+		clientNames = appendUnique(clientNames, sec.client)
+	}
+
+	if len(caNames) == 0 {
+		return errors.New("no ca_cert_name in the tls security of socle.yaml")
+	}
+
+	validity := time.Duration(certDays) * 24 * time.Hour
+
+	hosts := certHosts
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+		if name := os.Getenv("SERVER_NAME"); name != "" {
+			hosts = appendUnique(hosts, name)
+		}
+	}
+
+	for _, caName := range caNames {
+		ca, err := loadOrCreateAuthority(filepath.Join(s.RootPath, caName), cfg.Name, validity)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range serverNames {
+			certPEM, keyPEM, err := ca.Issue(hosts[0], hosts, x509.ExtKeyUsageServerAuth, validity)
+			if err != nil {
+				return err
+			}
+			if err := certs.WriteFiles(filepath.Join(s.RootPath, name), certPEM, keyPEM); err != nil {
+				return err
+			}
+			color.Green("Server certificate created in %s.crt", name)
+		}
+
+		for _, name := range clientNames {
+			certPEM, keyPEM, err := ca.Issue(cfg.Name+" client", nil, x509.ExtKeyUsageClientAuth, validity)
+			if err != nil {
+				return err
+			}
+			if err := certs.WriteFiles(filepath.Join(s.RootPath, name), certPEM, keyPEM); err != nil {
+				return err
+			}
+			color.Green("Client certificate created in %s.crt", name)
+		}
+	}
+
+	return nil
+}
+
+// loadOrCreateAuthority reuses the CA stored at base.crt/base.key, unless --force is given.
+func loadOrCreateAuthority(base, name string, validity time.Duration) (*certs.Authority, error) {
+	if !certForce && fileExists(base+".crt") && fileExists(base+".key") {
+		color.Yellow("Reusing the CA in %s.crt", base)
+		return certs.LoadAuthority(base+".crt", base+".key")
+	}
+
+	if name == "" {
+		name = "Socle"
+	}
+	ca, certPEM, keyPEM, err := certs.NewAuthority(name+" CA", validity)
+	if err != nil {
+		return nil, err
+	}
+	if err := certs.WriteFiles(base, certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	color.Green("CA created in %s.crt", base)
+	return ca, nil
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, e := range list {
+		if e == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	makeCmd.AddCommand(jobsCmd)
+}
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "creates the migrations of the table used by the sql job store",
+	Run: func(cmd *cobra.Command, args []string) {
+		doJobs()
+	},
+}
+
+func doJobs() error {
+	checkForDB()
+
+	upBytes, err := templateFS.ReadFile("templates/jobs/jobs_up.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+	downBytes, err := templateFS.ReadFile("templates/jobs/jobs_down.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = s.CreatePopMigration(upBytes, downBytes, "socle_jobs", "fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	return nil
+}
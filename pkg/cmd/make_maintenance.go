@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	makeCmd.AddCommand(maintenanceTableCmd)
+}
+
+var maintenanceTableCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "creates the migrations of the table used by the sql maintenance store",
+	Run: func(cmd *cobra.Command, args []string) {
+		doMaintenanceTable()
+	},
+}
+
+func doMaintenanceTable() error {
+	checkForDB()
+
+	upBytes, err := templateFS.ReadFile("templates/maintenance/maintenance_up.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+	downBytes, err := templateFS.ReadFile("templates/maintenance/maintenance_down.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = s.CreatePopMigration(upBytes, downBytes, "socle_maintenance", "fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	makeCmd.AddCommand(oauthTableCmd)
+}
+
+var oauthTableCmd = &cobra.Command{
+	Use:   "oauth",
+	Short: "creates the migrations of the table linking the oauth identities to the users",
+	Run: func(cmd *cobra.Command, args []string) {
+		doOAuthTable()
+	},
+}
+
+func doOAuthTable() error {
+	checkForDB()
+
+	upBytes, err := templateFS.ReadFile("templates/oauth/oauth_up.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+	downBytes, err := templateFS.ReadFile("templates/oauth/oauth_down.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = s.CreatePopMigration(upBytes, downBytes, "user_identities", "fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	makeCmd.AddCommand(tokensTableCmd)
+}
+
+var tokensTableCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "creates the migrations of the tables used by the sql token store",
+	Run: func(cmd *cobra.Command, args []string) {
+		doTokensTable()
+	},
+}
+
+func doTokensTable() error {
+	checkForDB()
+
+	upBytes, err := templateFS.ReadFile("templates/tokens/tokens_up.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+	downBytes, err := templateFS.ReadFile("templates/tokens/tokens_down.fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = s.CreatePopMigration(upBytes, downBytes, "socle_tokens", "fizz")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	return nil
+}
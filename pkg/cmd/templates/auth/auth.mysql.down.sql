@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS remember_tokens;
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,130 @@
+package control
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"strings"
+	"time"
+)
+
+// ErrUnauthorized is returned when a client does not prove it knows the shared key.
+var ErrUnauthorized = errors.New("control: unauthorized")
+
+// handshakeTimeout bounds the time a client has to authenticate.
+const handshakeTimeout = 5 * time.Second
+
+// Empty is the argument or reply of the commands that need none.
+type Empty struct{}
+
+// Status describes a running socle process.
+type Status struct {
+	Version     string
+	Entries     []string
+	Debug       bool
+	Maintenance bool
+	StartedAt   time.Time
+	Goroutines  int
+	DBOpenConns int
+}
+
+// ScheduledJob describes a job of the cron scheduler.
+type ScheduledJob struct {
+	ID   int
+	Name string
+	Next time.Time
+	Prev time.Time
+}
+
+// sign returns the proof that the client knows key for the challenge nonce.
+func sign(key []byte, nonce string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticate runs the server side of the handshake on conn: it sends a random nonce
+// and expects its HMAC-SHA256 with key in return, so that key never goes over the wire.
+func Authenticate(conn net.Conn, key []byte) error {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+	if _, err := fmt.Fprintln(conn, nonce); err != nil {
+		return err
+	}
+
+	proof, err := readLine(conn)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(proof), []byte(sign(key, nonce))) {
+		_, _ = fmt.Fprintln(conn, "denied")
+		return ErrUnauthorized
+	}
+
+	_, err = fmt.Fprintln(conn, "ok")
+	return err
+}
+
+// Dial connects to the control plane listening on address, over TLS when tlsConfig is
+// not nil, and authenticates with key.
+func Dial(network, address string, key []byte, tlsConfig *tls.Config) (*rpc.Client, error) {
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.Dial(network, address, tlsConfig)
+	} else {
+		conn, err = net.Dial(network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	nonce, err := readLine(conn)
+	if err == nil {
+		_, err = fmt.Fprintln(conn, sign(key, nonce))
+	}
+	var answer string
+	if err == nil {
+		answer, err = readLine(conn)
+	}
+	if err == nil && answer != "ok" {
+		err = ErrUnauthorized
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return rpc.NewClient(conn), nil
+}
+
+// readLine reads one line from conn byte per byte, so that nothing after it is consumed
+// before the connection is handed to the rpc codec.
+func readLine(conn net.Conn) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 256 {
+		if _, err := conn.Read(b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSpace(string(line)), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", errors.New("control: handshake line too long")
+}
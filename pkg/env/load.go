@@ -21,6 +21,11 @@ func Load(rootPath string) error {
 	return nil
 }
 
+// Reload reads the .env file again, overriding the variables already set.
+func Reload(rootPath string) error {
+	return godotenv.Overload(rootPath + "/.env")
+}
+
 func checkDotEnv(path string) error {
 	err := createFileIfNotExists(fmt.Sprintf("%s/.env", path))
 	if err != nil {
@@ -0,0 +1,331 @@
+// Package i18n translates the messages of an application, from catalogs of messages by
+// locale, and negotiates the locale of the requests.
+//
+// A catalog is a JSON or YAML file of messages, nested keys being joined with dots. The
+// messages take named parameters between braces:
+//
+//	welcome: "Bonjour {name}"
+//	validation:
+//	  required: "Ce champ est obligatoire"
+//
+//	i18n.T(ctx, "welcome", "name", user.FirstName)
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle holds the catalogs of the locales of an application.
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	catalogs map[string]map[string]string
+	locales  []string // the fallback first, for the matcher
+	matcher  language.Matcher
+}
+
+// NewBundle creates a bundle translating to fallback the messages missing in the locales.
+func NewBundle(fallback string) *Bundle {
+	b := &Bundle{
+		fallback: canonical(fallback),
+		catalogs: make(map[string]map[string]string),
+	}
+	b.locales = []string{b.fallback}
+	b.matcher = language.NewMatcher([]language.Tag{language.Make(b.fallback)})
+	return b
+}
+
+// canonical returns the BCP 47 form of locale: fr_FR gives fr-FR.
+func canonical(locale string) string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if tag, err := language.Parse(locale); err == nil {
+		return tag.String()
+	}
+	return locale
+}
+
+// Fallback returns the locale of the messages missing in the other locales.
+func (b *Bundle) Fallback() string {
+	return b.fallback
+}
+
+// Locales returns the locales of the bundle, the fallback first.
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.Clone(b.locales)
+}
+
+// Messages returns a copy of the catalog of locale.
+func (b *Bundle) Messages(locale string) map[string]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	catalog := b.catalogs[canonical(locale)]
+	messages := make(map[string]string, len(catalog))
+	for key, message := range catalog {
+		messages[key] = message
+	}
+	return messages
+}
+
+// AddMessages adds messages to the catalog of locale, replacing the messages of the same
+// keys.
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	locale = canonical(locale)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog, ok := b.catalogs[locale]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		b.catalogs[locale] = catalog
+	}
+	for key, message := range messages {
+		catalog[key] = message
+	}
+
+	if !slices.Contains(b.locales, locale) {
+		b.locales = append(b.locales, locale)
+		tags := make([]language.Tag, len(b.locales))
+		for i, l := range b.locales {
+			tags[i] = language.Make(l)
+		}
+		b.matcher = language.NewMatcher(tags)
+	}
+}
+
+// LoadDir loads the catalogs of dir: the <locale>.json, .yaml or .yml files, and the files
+// of the <locale> directories, like lang/fr.yaml or lang/fr/validation.yaml.
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS loads the catalogs of the directory dir of fsys, like LoadDir.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			if err := b.loadFile(fsys, name, strings.TrimSuffix(entry.Name(), path.Ext(name))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		files, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			if err := b.loadFile(fsys, path.Join(name, f.Name()), entry.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadFile loads the catalog file name of locale, skipping the files of other formats.
+func (b *Bundle) loadFile(fsys fs.FS, name, locale string) error {
+	var unmarshal func([]byte, any) error
+	switch path.Ext(name) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	default:
+		return nil
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	var tree map[string]any
+	if err := unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("i18n: %s: %w", name, err)
+	}
+
+	messages := make(map[string]string)
+	flatten(messages, "", tree)
+	b.AddMessages(locale, messages)
+	return nil
+}
+
+// flatten adds the messages of tree to messages, joining the nested keys with dots.
+func flatten(messages map[string]string, prefix string, tree map[string]any) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(messages, key, v)
+		case nil:
+		default:
+			messages[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Match returns the locale of the bundle best matching the preferred ones, Accept-Language
+// headers or locales, or the fallback when none matches.
+func (b *Bundle) Match(preferred ...string) string {
+	var tags []language.Tag
+	for _, p := range preferred {
+		if p == "" {
+			continue
+		}
+		parsed, _, err := language.ParseAcceptLanguage(p)
+		if err == nil {
+			tags = append(tags, parsed...)
+		}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, i, confidence := b.matcher.Match(tags...)
+	if confidence == language.No || i >= len(b.locales) {
+		return b.fallback
+	}
+	return b.locales[i]
+}
+
+// Lookup returns the message key of locale, else of its parent locales (fr for fr-CA),
+// else of the fallback.
+func (b *Bundle) Lookup(locale, key string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, l := range b.chain(canonical(locale)) {
+		if message, ok := b.catalogs[l][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// chain returns locale, its parents and the fallback.
+func (b *Bundle) chain(locale string) []string {
+	var chain []string
+	for l := locale; l != ""; {
+		chain = append(chain, l)
+		i := strings.LastIndex(l, "-")
+		if i < 0 {
+			break
+		}
+		l = l[:i]
+	}
+	return append(chain, b.fallback)
+}
+
+// Translate returns the message key of locale with its parameters, given by name and value
+// pairs or as a map[string]any. A missing message gives the key itself.
+func (b *Bundle) Translate(locale, key string, args ...any) string {
+	message, ok := b.Lookup(locale, key)
+	if !ok {
+		message = key
+	}
+	return Format(message, args...)
+}
+
+// Format replaces the {name} parameters of message, given by name and value pairs or as a
+// map[string]any.
+func Format(message string, args ...any) string {
+	if len(args) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+
+	params := make(map[string]any)
+	if len(args) == 1 {
+		if m, ok := args[0].(map[string]any); ok {
+			params = m
+		}
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		params[fmt.Sprint(args[i])] = args[i+1]
+	}
+
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// Localizer translates to the locale of a request.
+type Localizer struct {
+	Bundle *Bundle
+	Locale string
+}
+
+// Localizer returns the localizer of locale.
+func (b *Bundle) Localizer(locale string) *Localizer {
+	return &Localizer{Bundle: b, Locale: locale}
+}
+
+// T returns the message key with its parameters, see Bundle.Translate.
+func (l *Localizer) T(key string, args ...any) string {
+	return l.Bundle.Translate(l.Locale, key, args...)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (*Localizer, bool) {
+	l, ok := ctx.Value(contextKey{}).(*Localizer)
+	return l, ok
+}
+
+// Locale returns the locale of ctx, or "" if none.
+func Locale(ctx context.Context) string {
+	if l, ok := FromContext(ctx); ok {
+		return l.Locale
+	}
+	return ""
+}
+
+// T translates key to the locale of ctx, for the templ components:
+//
+//	<h1>{ i18n.T(ctx, "welcome", "name", user.FirstName) }</h1>
+//
+// Without a localizer in ctx, it returns the key with its parameters.
+func T(ctx context.Context, key string, args ...any) string {
+	if l, ok := FromContext(ctx); ok {
+		return l.T(key, args...)
+	}
+	return Format(key, args...)
+}
+
+// Func returns T bound to ctx, for the jet variables and the data of the mail templates:
+//
+//	vars.Set("t", i18n.Func(r.Context()))   // {{ t("welcome", "name", user.FirstName) }}
+//	data["T"] = i18n.Func(ctx)               // {{ call .T "welcome" "name" .FirstName }}
+func Func(ctx context.Context) func(key string, args ...any) string {
+	return func(key string, args ...any) string {
+		return T(ctx, key, args...)
+	}
+}
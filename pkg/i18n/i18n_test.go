@@ -0,0 +1,113 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := NewBundle("en")
+	fsys := fstest.MapFS{
+		"lang/en.yaml":       {Data: []byte("welcome: \"Hello {name}\"\nvalidation:\n  required: \"This field is required\"\n  email: \"Invalid email\"\n")},
+		"lang/fr/socle.yaml": {Data: []byte("welcome: \"Bonjour {name}\"\nvalidation:\n  required: \"Ce champ est obligatoire\"\n")},
+		"lang/fr-CA.json":    {Data: []byte(`{"welcome": "Allô {name}"}`)},
+		"lang/pt_BR.yml":     {Data: []byte("welcome: \"Olá {name}\"\n")},
+		"lang/README.md":     {Data: []byte("# catalogs")},
+	}
+	if err := b.LoadFS(fsys, "lang"); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLoadFS(t *testing.T) {
+	b := newTestBundle(t)
+	want := []string{"en", "fr", "fr-CA", "pt-BR"}
+	if got := b.Locales(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Locales = %v, want %v", got, want)
+	}
+	if got := b.Messages("fr")["validation.required"]; got != "Ce champ est obligatoire" {
+		t.Errorf("nested key validation.required = %q", got)
+	}
+
+	err := NewBundle("en").LoadFS(fstest.MapFS{"lang/de.yaml": {Data: []byte("welcome: [")}}, "lang")
+	if err == nil || !strings.Contains(err.Error(), "lang/de.yaml") {
+		t.Errorf("invalid catalog: err = %v, want the name of the file", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := newTestBundle(t)
+	tests := []struct {
+		name      string
+		preferred []string
+		want      string
+	}{
+		{"nothing", nil, "en"},
+		{"empty header", []string{""}, "en"},
+		{"supported", []string{"fr"}, "fr"},
+		{"region", []string{"fr-FR,fr;q=0.9,en;q=0.8"}, "fr"},
+		{"exact region", []string{"fr-CA"}, "fr-CA"},
+		{"catalog file pt_BR", []string{"pt-BR"}, "pt-BR"},
+		{"quality order", []string{"en;q=0.2, fr;q=0.8"}, "fr"},
+		{"unsupported first", []string{"de-DE,de;q=0.9,fr;q=0.5"}, "fr"},
+		{"unsupported", []string{"de-DE,de;q=0.9"}, "en"},
+		{"wildcard", []string{"*"}, "en"},
+		{"invalid header", []string{"!!!"}, "en"},
+		{"session before header", []string{"fr", "en-US,en"}, "fr"},
+		{"unsupported session", []string{"de", "fr"}, "fr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Match(tt.preferred...); got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.preferred, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b := newTestBundle(t)
+	tests := []struct {
+		locale, key string
+		args        []any
+		want        string
+	}{
+		{"fr", "welcome", []any{"name", "Ada"}, "Bonjour Ada"},
+		{"fr_CA", "welcome", []any{"name", "Ada"}, "Allô Ada"},
+		// from the parent locale, then the fallback
+		{"fr-CA", "validation.required", nil, "Ce champ est obligatoire"},
+		{"fr-CA", "validation.email", nil, "Invalid email"},
+		{"de", "welcome", []any{map[string]any{"name": "Ada"}}, "Hello Ada"},
+		{"en", "missing.key", nil, "missing.key"},
+		{"en", "welcome", nil, "Hello {name}"},
+		{"en", "welcome", []any{"name"}, "Hello {name}"},
+	}
+	for _, tt := range tests {
+		if got := b.Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%s, %s, %v) = %q, want %q", tt.locale, tt.key, tt.args, got, tt.want)
+		}
+	}
+	if _, ok := b.Lookup("fr", "missing.key"); ok {
+		t.Error("Lookup of a missing key succeeded")
+	}
+}
+
+func TestContext(t *testing.T) {
+	b := newTestBundle(t)
+	ctx := context.Background()
+	if got := T(ctx, "welcome", "name", "Ada"); got != "welcome" || Locale(ctx) != "" {
+		t.Errorf("T without localizer = %q", got)
+	}
+
+	ctx = NewContext(ctx, b.Localizer("fr"))
+	if Locale(ctx) != "fr" {
+		t.Errorf("Locale = %q, want fr", Locale(ctx))
+	}
+	if got := Func(ctx)("welcome", "name", "Ada"); got != "Bonjour Ada" {
+		t.Errorf("Func = %q", got)
+	}
+}
@@ -0,0 +1,201 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// BadgerStore stores jobs in badger, for single node deployments. Ready jobs are indexed
+// by keys sorted on the time they can run at.
+type BadgerStore struct {
+	DB *badger.DB
+}
+
+// NewBadgerStore creates a store using db.
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{DB: db}
+}
+
+const (
+	badgerDataPrefix  = "jobs:data:"
+	badgerReadyPrefix = "jobs:ready:"
+	badgerDeadPrefix  = "jobs:dead:"
+)
+
+func readyKey(job *Job) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", badgerReadyPrefix, job.RunAt.UnixNano(), job.ID))
+}
+
+func dataKey(id string) []byte {
+	return []byte(badgerDataPrefix + id)
+}
+
+// update runs fn in a read-write transaction, retrying when it conflicts with another worker.
+func (s *BadgerStore) update(fn func(txn *badger.Txn) error) error {
+	for {
+		err := s.DB.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+}
+
+func getJob(txn *badger.Txn, key []byte) (*Job, error) {
+	item, err := txn.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func setJob(txn *badger.Txn, key []byte, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return txn.Set(key, data)
+}
+
+func (s *BadgerStore) Push(ctx context.Context, job *Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		if err := setJob(txn, dataKey(job.ID), job); err != nil {
+			return err
+		}
+		return txn.Set(readyKey(job), []byte(job.ID))
+	})
+}
+
+func (s *BadgerStore) Reserve(ctx context.Context, visibility time.Duration) (*Job, error) {
+	var job *Job
+	err := s.update(func(txn *badger.Txn) error {
+		job = nil
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(badgerReadyPrefix)
+		it := txn.NewIterator(opts)
+		it.Rewind()
+		if !it.Valid() {
+			it.Close()
+			return ErrNoJob
+		}
+		key := it.Item().KeyCopy(nil)
+		it.Close()
+
+		parts := strings.SplitN(strings.TrimPrefix(string(key), badgerReadyPrefix), ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid job key %q", key)
+		}
+
+		now := time.Now()
+		if parts[0] > fmt.Sprintf("%020d", now.UnixNano()) {
+			return ErrNoJob
+		}
+
+		j, err := getJob(txn, dataKey(parts[1]))
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+
+		j.Attempts++
+		j.RunAt = now.Add(visibility)
+		if err := setJob(txn, dataKey(j.ID), j); err != nil {
+			return err
+		}
+		if err := txn.Set(readyKey(j), []byte(j.ID)); err != nil {
+			return err
+		}
+		job = j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// remove deletes the data and the ready index of the job with id.
+func (s *BadgerStore) remove(txn *badger.Txn, id string) error {
+	stored, err := getJob(txn, dataKey(id))
+	if err != nil {
+		return err
+	}
+	if err := txn.Delete(readyKey(stored)); err != nil {
+		return err
+	}
+	return txn.Delete(dataKey(id))
+}
+
+func (s *BadgerStore) Delete(ctx context.Context, job *Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		return s.remove(txn, job.ID)
+	})
+}
+
+func (s *BadgerStore) Retry(ctx context.Context, job *Job) error {
+	return s.update(func(txn *badger.Txn) error {
+		if err := s.remove(txn, job.ID); err != nil {
+			return err
+		}
+		if err := setJob(txn, dataKey(job.ID), job); err != nil {
+			return err
+		}
+		return txn.Set(readyKey(job), []byte(job.ID))
+	})
+}
+
+func (s *BadgerStore) Bury(ctx context.Context, job *Job) error {
+	now := time.Now()
+	job.FailedAt = &now
+	return s.update(func(txn *badger.Txn) error {
+		if err := s.remove(txn, job.ID); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s%020d:%s", badgerDeadPrefix, now.UnixNano(), job.ID)
+		return setJob(txn, []byte(key), job)
+	})
+}
+
+func (s *BadgerStore) Dead(ctx context.Context, limit int) ([]*Job, error) {
+	var jobs []*Job
+	err := s.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		opts.Prefix = []byte(badgerDeadPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// reverse iteration starts from the last key of the prefix
+		for it.Seek([]byte(badgerDeadPrefix + "\xff")); it.Valid() && len(jobs) < limit; it.Next() {
+			data, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, &job)
+		}
+		return nil
+	})
+	return jobs, err
+}
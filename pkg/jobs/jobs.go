@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Different types of error returned by the stores and the queue
+var (
+	ErrNoJob      = errors.New("no job ready")
+	ErrNoHandler  = errors.New("no handler registered for job")
+	ErrJobTimeout = errors.New("job exceeded its visibility timeout")
+)
+
+// Job is a unit of background work. Name identifies the handler that processes it,
+// and Payload holds its JSON encoded arguments.
+type Job struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	FailedAt    *time.Time      `json:"failed_at,omitempty"`
+}
+
+// Handler processes a job. Returning an error schedules a retry, or moves the job to
+// the dead-letter storage once its attempts are exhausted.
+type Handler func(ctx context.Context, job *Job) error
+
+// Handle adapts a function taking a typed payload to a Handler, decoding the JSON payload
+// of the job into T.
+func Handle[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, job *Job) error {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return fmt.Errorf("decode payload of job %s: %w", job.Name, err)
+			}
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Option customizes a job when it is enqueued.
+type Option func(*Job)
+
+// Delay postpones the first run of the job by d.
+func Delay(d time.Duration) Option {
+	return func(j *Job) {
+		j.RunAt = time.Now().Add(d)
+	}
+}
+
+// At schedules the first run of the job at t.
+func At(t time.Time) Option {
+	return func(j *Job) {
+		j.RunAt = t
+	}
+}
+
+// Retries sets the maximum number of attempts of the job, overriding the queue default.
+func Retries(n int) Option {
+	return func(j *Job) {
+		j.MaxAttempts = n
+	}
+}
+
+// Queue enqueues jobs into a Store and dispatches them to their registered handler.
+type Queue struct {
+	Store       Store
+	MaxAttempts int
+	ErrorLog    *log.Logger
+	InfoLog     *log.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New creates a queue backed by store.
+func New(store Store, maxAttempts int) *Queue {
+	return &Queue{
+		Store:       store,
+		MaxAttempts: maxAttempts,
+		ErrorLog:    log.New(os.Stdout, "jobs ERROR\t", log.Ldate|log.Ltime),
+		InfoLog:     log.New(os.Stdout, "jobs INFO\t", log.Ldate|log.Ltime),
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Register associates a handler with the jobs named name.
+func (q *Queue) Register(name string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[name] = h
+}
+
+func (q *Queue) handler(name string) (Handler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	h, ok := q.handlers[name]
+	return h, ok
+}
+
+// Enqueue saves a job named name with payload encoded as JSON. By default the job
+// is ready to run immediately and can be attempted q.MaxAttempts times.
+func (q *Queue) Enqueue(ctx context.Context, name string, payload any, opts ...Option) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:          id.String(),
+		Name:        name,
+		Payload:     data,
+		MaxAttempts: q.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if job.MaxAttempts < 1 {
+		job.MaxAttempts = 1
+	}
+
+	if err := q.Store.Push(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Dead returns up to limit jobs from the dead-letter storage, most recent first.
+func (q *Queue) Dead(ctx context.Context, limit int) ([]*Job, error) {
+	return q.Store.Dead(ctx, limit)
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisStore stores jobs in redis. Ready jobs are members of a sorted set scored by
+// the time they can run at; reserving a job pushes its score after the visibility
+// timeout, so it becomes ready again if the worker dies before deleting it.
+type RedisStore struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// NewRedisStore creates a store using the connections of pool, with keys prefixed by prefix.
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{
+		Pool:   pool,
+		Prefix: prefix,
+	}
+}
+
+// reserveScript reserves the first ready job. KEYS: ready, data, attempts.
+// ARGV: now, visible again at.
+var reserveScript = redis.NewScript(3, `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local id = ids[1]
+redis.call('ZADD', KEYS[1], ARGV[2], id)
+local attempts = redis.call('HINCRBY', KEYS[3], id, 1)
+return {redis.call('HGET', KEYS[2], id), attempts}
+`)
+
+func (s *RedisStore) key(name string) string {
+	return s.Prefix + "jobs:" + name
+}
+
+func (s *RedisStore) conn(ctx context.Context) (redis.Conn, error) {
+	return s.Pool.GetContext(ctx)
+}
+
+func (s *RedisStore) Push(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("HSET", s.key("data"), job.ID, data)
+	_ = conn.Send("HSET", s.key("attempts"), job.ID, job.Attempts)
+	_ = conn.Send("ZADD", s.key("ready"), job.RunAt.UnixMilli(), job.ID)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, visibility time.Duration) (*Job, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	reply, err := redis.Values(reserveScript.Do(conn,
+		s.key("ready"), s.key("data"), s.key("attempts"),
+		now.UnixMilli(), now.Add(visibility).UnixMilli()))
+	if err == redis.ErrNil {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data     []byte
+		attempts int
+	)
+	if _, err := redis.Scan(reply, &data, &attempts); err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	job.Attempts = attempts
+	job.RunAt = now.Add(visibility)
+	return &job, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, job *Job) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.key("ready"), job.ID)
+	_ = conn.Send("HDEL", s.key("data"), job.ID)
+	_ = conn.Send("HDEL", s.key("attempts"), job.ID)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) Retry(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("HSET", s.key("data"), job.ID, data)
+	_ = conn.Send("HSET", s.key("attempts"), job.ID, job.Attempts)
+	_ = conn.Send("ZADD", s.key("ready"), job.RunAt.UnixMilli(), job.ID)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) Bury(ctx context.Context, job *Job) error {
+	now := time.Now()
+	job.FailedAt = &now
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.key("ready"), job.ID)
+	_ = conn.Send("HDEL", s.key("data"), job.ID)
+	_ = conn.Send("HDEL", s.key("attempts"), job.ID)
+	_ = conn.Send("LPUSH", s.key("dead"), data)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) Dead(ctx context.Context, limit int) ([]*Job, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("LRANGE", s.key("dead"), 0, strconv.Itoa(limit-1)))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, data := range values {
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/socle-framework/socle/internal/sqldialect"
+)
+
+// SQLStore stores jobs in a postgres or mysql table, created by `socle make jobs`.
+// Ready jobs are reserved with SELECT ... FOR UPDATE SKIP LOCKED, so several workers can
+// share the table.
+type SQLStore struct {
+	DB     *sql.DB
+	DBType string
+	Table  string
+}
+
+// NewSQLStore creates a store using the socle_jobs table of db.
+func NewSQLStore(db *sql.DB, dbType string) *SQLStore {
+	return &SQLStore{
+		DB:     db,
+		DBType: dbType,
+		Table:  "socle_jobs",
+	}
+}
+
+const sqlColumns = "id, name, payload, attempts, max_attempts, run_at, last_error, created_at, failed_at"
+
+// rebind replaces the ? placeholders of query by $n ones for postgres.
+func (s *SQLStore) rebind(query string) string {
+	return sqldialect.Rebind(s.DBType, query)
+}
+
+func (s *SQLStore) Push(ctx context.Context, job *Job) error {
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %s (id, name, payload, attempts, max_attempts, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, s.Table))
+
+	_, err := s.DB.ExecContext(ctx, query,
+		job.ID, job.Name, string(job.Payload), job.Attempts, job.MaxAttempts, job.RunAt, job.CreatedAt, now)
+	return err
+}
+
+func (s *SQLStore) Reserve(ctx context.Context, visibility time.Duration) (*Job, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf(`SELECT %s FROM %s
+		WHERE failed_at IS NULL AND run_at <= ?
+		ORDER BY run_at LIMIT 1 FOR UPDATE SKIP LOCKED`, sqlColumns, s.Table))
+
+	job, err := scanJob(tx.QueryRowContext(ctx, query, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.Attempts++
+	job.RunAt = now.Add(visibility)
+	query = s.rebind(fmt.Sprintf(`UPDATE %s SET attempts = ?, run_at = ?, updated_at = ? WHERE id = ?`, s.Table))
+	if _, err := tx.ExecContext(ctx, query, job.Attempts, job.RunAt, now, job.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, job *Job) error {
+	query := s.rebind(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.Table))
+	_, err := s.DB.ExecContext(ctx, query, job.ID)
+	return err
+}
+
+func (s *SQLStore) Retry(ctx context.Context, job *Job) error {
+	query := s.rebind(fmt.Sprintf(`UPDATE %s SET run_at = ?, attempts = ?, last_error = ?, updated_at = ? WHERE id = ?`, s.Table))
+	_, err := s.DB.ExecContext(ctx, query, job.RunAt, job.Attempts, job.LastError, time.Now(), job.ID)
+	return err
+}
+
+func (s *SQLStore) Bury(ctx context.Context, job *Job) error {
+	now := time.Now()
+	job.FailedAt = &now
+	query := s.rebind(fmt.Sprintf(`UPDATE %s SET failed_at = ?, last_error = ?, updated_at = ? WHERE id = ?`, s.Table))
+	_, err := s.DB.ExecContext(ctx, query, now, job.LastError, now, job.ID)
+	return err
+}
+
+func (s *SQLStore) Dead(ctx context.Context, limit int) ([]*Job, error) {
+	query := s.rebind(fmt.Sprintf(`SELECT %s FROM %s WHERE failed_at IS NOT NULL
+		ORDER BY failed_at DESC LIMIT ?`, sqlColumns, s.Table))
+
+	rows, err := s.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*Job, error) {
+	var (
+		job       Job
+		payload   string
+		lastError sql.NullString
+		failedAt  sql.NullTime
+	)
+
+	err := row.Scan(&job.ID, &job.Name, &payload, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &lastError, &job.CreatedAt, &failedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Payload = []byte(payload)
+	job.LastError = lastError.String
+	if failedAt.Valid {
+		job.FailedAt = &failedAt.Time
+	}
+	return &job, nil
+}
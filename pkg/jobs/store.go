@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists jobs. Implementations must be safe for concurrent use by several
+// workers, possibly running in different processes.
+type Store interface {
+	// Push saves a new job, ready to run at job.RunAt.
+	Push(ctx context.Context, job *Job) error
+	// Reserve returns the next job ready to run, with its attempts incremented, and hides
+	// it from other workers for visibility. It returns ErrNoJob when no job is ready.
+	Reserve(ctx context.Context, visibility time.Duration) (*Job, error)
+	// Delete removes a processed job.
+	Delete(ctx context.Context, job *Job) error
+	// Retry saves the attempts and the error of a failed job and makes it ready again at
+	// job.RunAt.
+	Retry(ctx context.Context, job *Job) error
+	// Bury moves a job to the dead-letter storage.
+	Bury(ctx context.Context, job *Job) error
+	// Dead returns up to limit jobs from the dead-letter storage, most recent first.
+	Dead(ctx context.Context, limit int) ([]*Job, error)
+}
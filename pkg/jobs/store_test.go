@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+func newJob(id string, runAt time.Time) *Job {
+	return &Job{ID: id, Name: "send", Payload: []byte(`{"to":"a@b.c"}`), MaxAttempts: 3, RunAt: runAt, CreatedAt: time.Now()}
+}
+
+// testStore checks the behavior shared by the stores.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	reserve := func(visibility time.Duration) *Job {
+		t.Helper()
+		job, err := s.Reserve(ctx, visibility)
+		if err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		return job
+	}
+	empty := func(step string) {
+		t.Helper()
+		if job, err := s.Reserve(ctx, time.Minute); !errors.Is(err, ErrNoJob) {
+			t.Fatalf("%s: Reserve = %+v, %v, want ErrNoJob", step, job, err)
+		}
+	}
+
+	empty("empty store")
+	if err := s.Push(ctx, newJob("later", time.Now().Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	empty("job scheduled later")
+	if err := s.Push(ctx, newJob("now", time.Now().Add(-time.Second))); err != nil {
+		t.Fatal(err)
+	}
+
+	job := reserve(time.Minute)
+	if job.ID != "now" || job.Attempts != 1 || string(job.Payload) != `{"to":"a@b.c"}` {
+		t.Fatalf("Reserve = %+v, want job now at its first attempt", job)
+	}
+	empty("job reserved")
+
+	// Retry saves the attempts and the error
+	job.Attempts = 2
+	job.LastError = "smtp down"
+	job.RunAt = time.Now()
+	if err := s.Retry(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	job = reserve(10 * time.Millisecond)
+	if job.ID != "now" || job.Attempts != 3 || job.LastError != "smtp down" {
+		t.Fatalf("Reserve after Retry = %+v, want attempt 3 with the last error", job)
+	}
+
+	// a job not deleted is ready again after the visibility timeout
+	time.Sleep(20 * time.Millisecond)
+	job = reserve(time.Minute)
+	if job.ID != "now" || job.Attempts != 4 {
+		t.Fatalf("Reserve after the visibility timeout = %+v, want attempt 4", job)
+	}
+
+	if err := s.Bury(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	empty("job buried")
+	dead, err := s.Dead(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != "now" || dead[0].FailedAt == nil || dead[0].LastError != "smtp down" {
+		t.Fatalf("Dead = %+v, want the buried job", dead)
+	}
+
+	if err := s.Push(ctx, newJob("done", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	job = reserve(time.Minute)
+	if err := s.Delete(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	empty("job deleted")
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", addr) }}
+	t.Cleanup(func() { pool.Close() })
+
+	testStore(t, NewRedisStore(pool, "app:"))
+
+	for _, key := range mr.Keys() {
+		if !strings.HasPrefix(key, "app:jobs:") {
+			t.Errorf("key %s outside of the prefix", key)
+		}
+	}
+}
+
+func TestBadgerStore(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	testStore(t, NewBadgerStore(db))
+}
@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WorkerConfig holds the settings of the workers pulling jobs from a queue.
+type WorkerConfig struct {
+	Concurrency  int
+	PollInterval time.Duration
+	Visibility   time.Duration
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	// Drain is the time given to the running jobs to finish once the workers stop, before
+	// their context is canceled. The jobs canceled this way are released without counting
+	// the attempt.
+	Drain time.Duration
+}
+
+// Work runs cfg.Concurrency workers processing the jobs of the queue until ctx is done.
+// The workers then stop reserving jobs, and Work returns once the jobs being processed
+// are finished, or canceled after cfg.Drain.
+func (q *Queue) Work(ctx context.Context, cfg WorkerConfig) {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	q.InfoLog.Printf("Starting %d job workers", cfg.Concurrency)
+
+	// the handlers outlive ctx for cfg.Drain
+	jobCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	defer abort()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-jobCtx.Done():
+			return
+		}
+		timer := time.NewTimer(cfg.Drain)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			abort()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, jobCtx, cfg)
+		}()
+	}
+	wg.Wait()
+}
+
+// work reserves the jobs until ctx is done, and runs them with jobCtx.
+func (q *Queue) work(ctx, jobCtx context.Context, cfg WorkerConfig) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := q.Store.Reserve(ctx, cfg.Visibility)
+		if err != nil {
+			if !errors.Is(err, ErrNoJob) && ctx.Err() == nil {
+				q.ErrorLog.Println(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cfg.PollInterval):
+			}
+			continue
+		}
+
+		q.process(jobCtx, cfg, job)
+	}
+}
+
+// process runs the handler of job and records its outcome. The outcome is saved even
+// when ctx is canceled, so that a job finished during shutdown is not run twice. A job
+// failing because ctx was canceled is released as it was before the attempt.
+func (q *Queue) process(ctx context.Context, cfg WorkerConfig, job *Job) {
+	err := q.run(ctx, cfg, job)
+	storeCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := q.Store.Delete(storeCtx, job); err != nil {
+			q.ErrorLog.Println(err)
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		q.InfoLog.Printf("job %s (%s) interrupted by the shutdown, released", job.ID, job.Name)
+		job.Attempts--
+		job.RunAt = time.Now()
+		if err := q.Store.Retry(storeCtx, job); err != nil {
+			q.ErrorLog.Println(err)
+		}
+		return
+	}
+
+	job.LastError = err.Error()
+	if job.Attempts >= job.MaxAttempts || errors.Is(err, ErrNoHandler) {
+		q.ErrorLog.Printf("job %s (%s) failed after %d attempts: %v", job.ID, job.Name, job.Attempts, err)
+		if err := q.Store.Bury(storeCtx, job); err != nil {
+			q.ErrorLog.Println(err)
+		}
+		return
+	}
+
+	job.RunAt = time.Now().Add(backoff(cfg, job.Attempts))
+	if err := q.Store.Retry(storeCtx, job); err != nil {
+		q.ErrorLog.Println(err)
+	}
+}
+
+func (q *Queue) run(ctx context.Context, cfg WorkerConfig, job *Job) (err error) {
+	h, ok := q.handler(job.Name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoHandler, job.Name)
+	}
+
+	if cfg.Visibility > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Visibility)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	err = h(ctx, job)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %v", ErrJobTimeout, err)
+	}
+	return err
+}
+
+// backoff returns the delay before the next attempt: BackoffBase doubled for every
+// attempt already made, capped to BackoffMax.
+func backoff(cfg WorkerConfig, attempts int) time.Duration {
+	delay := cfg.BackoffBase
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if cfg.BackoffMax > 0 && delay >= cfg.BackoffMax {
+			return cfg.BackoffMax
+		}
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+func newBadgerQueue(t *testing.T) *Queue {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	q := New(NewBadgerStore(db), 1)
+	q.InfoLog = log.New(io.Discard, "", 0)
+	q.ErrorLog = log.New(io.Discard, "", 0)
+	return q
+}
+
+// workUntil runs the workers of q until the handler of job "block" has started, then
+// stops them and waits for Work to return.
+func workUntil(t *testing.T, q *Queue, started <-chan struct{}, drain time.Duration) {
+	t.Helper()
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Work(ctx, WorkerConfig{PollInterval: 5 * time.Millisecond, Visibility: time.Minute, Drain: drain})
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not started")
+	}
+	stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Work did not return")
+	}
+}
+
+func TestWorkDrain(t *testing.T) {
+	q := newBadgerQueue(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	q.Register("block", func(ctx context.Context, job *Job) error {
+		close(started)
+		<-release
+		close(finished)
+		return ctx.Err()
+	})
+	if _, err := q.Enqueue(context.Background(), "block", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the job finishes during the drain
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	workUntil(t, q, started, time.Minute)
+
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Work returned before the running job finished")
+	}
+	if _, err := q.Store.Reserve(context.Background(), time.Minute); !errors.Is(err, ErrNoJob) {
+		t.Errorf("Reserve after the job succeeded: err = %v, want ErrNoJob", err)
+	}
+}
+
+func TestWorkDrainTimeout(t *testing.T) {
+	q := newBadgerQueue(t)
+	started := make(chan struct{})
+	q.Register("block", func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	// a single attempt: the interrupted job would be buried if the attempt counted
+	if _, err := q.Enqueue(context.Background(), "block", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	workUntil(t, q, started, 10*time.Millisecond)
+
+	dead, err := q.Dead(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 0 {
+		t.Fatalf("interrupted job buried: %+v", dead[0])
+	}
+	job, err := q.Store.Reserve(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatalf("interrupted job not released: %v", err)
+	}
+	if job.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1: the interrupted attempt was counted", job.Attempts)
+	}
+}
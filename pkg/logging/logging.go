@@ -0,0 +1,125 @@
+// Package logging builds the structured loggers of socle: leveled slog loggers writing
+// text or JSON, enriched with the fields attached to the request context, and sampled
+// so a hot path cannot flood the output.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configure a logger.
+type Options struct {
+	// Format is "json" or "text".
+	Format string
+	// Level is the minimum level logged; it can be changed while the logger runs.
+	Level *slog.LevelVar
+	// AddSource adds the file and line of the call to the records.
+	AddSource bool
+	// Sampling drops the repeated records below the error level; nil logs everything.
+	Sampling *Sampling
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, opts Options) *slog.Logger {
+	level := opts.Level
+	if level == nil {
+		level = new(slog.LevelVar)
+	}
+
+	hopts := &slog.HandlerOptions{Level: level, AddSource: opts.AddSource}
+
+	var h slog.Handler
+	if strings.EqualFold(opts.Format, "json") {
+		h = slog.NewJSONHandler(w, hopts)
+	} else {
+		h = slog.NewTextHandler(w, hopts)
+	}
+
+	h = &contextHandler{next: h}
+	if opts.Sampling != nil && opts.Sampling.First > 0 {
+		h = newSampler(h, *opts.Sampling)
+	}
+	return slog.New(h)
+}
+
+// ParseLevel returns the level named s (debug, info, warn or error), or fallback.
+func ParseLevel(s string, fallback slog.Level) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return fallback
+	}
+	return l
+}
+
+type fieldsKey struct{}
+
+// fields holds the attributes of a request. It is shared by pointer so that the
+// attributes added by an inner middleware, like the authenticated user, are seen by
+// the outer ones, like the access log.
+type fields struct {
+	attrs []slog.Attr
+}
+
+// NewContext returns a copy of ctx carrying attrs, which are added to every record
+// logged with ctx.
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	f := &fields{}
+	if parent, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.attrs = append(f.attrs, parent.attrs...)
+	}
+	f.attrs = append(f.attrs, attrs...)
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// AddAttrs adds attrs to the fields carried by ctx, so they are also logged by the
+// callers that created the context. It does nothing if ctx has no fields.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.attrs = append(f.attrs, attrs...)
+	}
+}
+
+// Attrs returns the fields carried by ctx.
+func Attrs(ctx context.Context) []slog.Attr {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		return f.attrs
+	}
+	return nil
+}
+
+// contextHandler adds the fields carried by the context, and the ids of its trace span,
+// to the records.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := Attrs(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()))
+	}
+	if len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s    string
+		want slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"error", slog.LevelError},
+		{"error+2", slog.LevelError + 2},
+		{"", slog.LevelWarn},
+		{"verbose", slog.LevelWarn},
+	}
+	for _, tt := range tests {
+		if got := ParseLevel(tt.s, slog.LevelWarn); got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `"msg":"started"`},
+		{"JSON", `"msg":"started"`},
+		{"text", `msg=started`},
+		{"", `msg=started`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		level := new(slog.LevelVar)
+		logger := New(&buf, Options{Format: tt.format, Level: level})
+
+		logger.Debug("hidden")
+		logger.Info("started")
+		if out := buf.String(); !strings.Contains(out, tt.want) || strings.Contains(out, "hidden") {
+			t.Errorf("format %q: output %q, want %s at the info level", tt.format, out, tt.want)
+		}
+
+		buf.Reset()
+		level.Set(slog.LevelDebug)
+		logger.Debug("shown")
+		if !strings.Contains(buf.String(), "shown") {
+			t.Errorf("format %q: level change ignored", tt.format)
+		}
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Format: "json"})
+
+	ctx := NewContext(context.Background(), slog.String("entry", "api"))
+	inner := NewContext(ctx, slog.String("request_id", "r1"))
+	// added by an inner middleware, seen by the outer ones sharing the fields
+	AddAttrs(inner, slog.String("user", "ada"))
+	AddAttrs(context.Background(), slog.String("lost", "x"))
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}, TraceFlags: trace.FlagsSampled,
+	})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]string
+	}{
+		{"no fields", context.Background(), map[string]string{}},
+		{"outer", ctx, map[string]string{"entry": "api"}},
+		{"inner", inner, map[string]string{"entry": "api", "request_id": "r1", "user": "ada"}},
+		{"span", trace.ContextWithSpanContext(ctx, sc), map[string]string{
+			"entry": "api", "trace_id": sc.TraceID().String(), "span_id": sc.SpanID().String(),
+		}},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		logger.InfoContext(tt.ctx, "request")
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"time", "level", "msg"} {
+			delete(record, key)
+		}
+		if len(record) != len(tt.want) {
+			t.Errorf("%s: record fields %v, want %v", tt.name, record, tt.want)
+		}
+		for key, value := range tt.want {
+			if record[key] != value {
+				t.Errorf("%s: %s = %v, want %s", tt.name, key, record[key], value)
+			}
+		}
+	}
+}
+
+// countHandler counts the records it handles.
+type countHandler struct {
+	n *int
+}
+
+func (h countHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h countHandler) Handle(context.Context, slog.Record) error {
+	*h.n++
+	return nil
+}
+func (h countHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h countHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestSampler(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		cfg     Sampling
+		level   slog.Level
+		records int
+		at      time.Duration // between two records
+		want    int
+	}{
+		{name: "first only", cfg: Sampling{First: 3}, level: slog.LevelInfo, records: 10, want: 3},
+		{name: "thereafter", cfg: Sampling{First: 3, Thereafter: 2}, level: slog.LevelInfo, records: 10, want: 6},
+		{name: "errors kept", cfg: Sampling{First: 1}, level: slog.LevelError, records: 10, want: 10},
+		// a new interval every 4 records
+		{name: "interval", cfg: Sampling{First: 2, Interval: time.Second}, level: slog.LevelWarn, records: 12, at: 250 * time.Millisecond, want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			h := newSampler(countHandler{n: &n}, tt.cfg)
+			// the derived handlers share the counts
+			derived := h.WithAttrs([]slog.Attr{slog.String("k", "v")})
+			for i := 0; i < tt.records; i++ {
+				handler := slog.Handler(h)
+				if i%2 == 1 {
+					handler = derived
+				}
+				r := slog.NewRecord(start.Add(time.Duration(i)*tt.at), tt.level, "hot path", 0)
+				if err := handler.Handle(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n != tt.want {
+				t.Errorf("%d records logged, want %d", n, tt.want)
+			}
+
+			// the other messages have their own counts
+			if err := h.Handle(context.Background(), slog.NewRecord(start, tt.level, "other", 0)); err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want+1 {
+				t.Error("record of another message dropped")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sampling logs, for each level and message, the First records of every Interval, then
+// one record out of Thereafter (none if Thereafter is 0). Errors are never sampled.
+type Sampling struct {
+	First      int
+	Thereafter int
+	Interval   time.Duration
+}
+
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+// samples is shared by the handlers derived from a sampler with WithAttrs or WithGroup.
+type samples struct {
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+}
+
+type sampler struct {
+	next    slog.Handler
+	cfg     Sampling
+	samples *samples
+}
+
+func newSampler(next slog.Handler, cfg Sampling) *sampler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &sampler{next: next, cfg: cfg, samples: &samples{counts: make(map[sampleKey]*sampleCount)}}
+}
+
+func (h *sampler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *sampler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError || h.keep(r) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *sampler) keep(r slog.Record) bool {
+	s := h.samples
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sampleKey{r.Level, r.Message}
+	c, ok := s.counts[key]
+	if !ok || r.Time.Sub(c.start) >= h.cfg.Interval {
+		// drop the windows that ended, so the map stays small
+		for k, old := range s.counts {
+			if r.Time.Sub(old.start) >= h.cfg.Interval {
+				delete(s.counts, k)
+			}
+		}
+		c = &sampleCount{start: r.Time}
+		s.counts[key] = c
+	}
+
+	c.n++
+	if c.n <= h.cfg.First {
+		return true
+	}
+	return h.cfg.Thereafter > 0 && (c.n-h.cfg.First)%h.cfg.Thereafter == 0
+}
+
+func (h *sampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampler{next: h.next.WithAttrs(attrs), cfg: h.cfg, samples: h.samples}
+}
+
+func (h *sampler) WithGroup(name string) slog.Handler {
+	return &sampler{next: h.next.WithGroup(name), cfg: h.cfg, samples: h.samples}
+}
@@ -0,0 +1,145 @@
+package maintenance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetryAfter is the Retry-After sent when no end time is expected.
+const DefaultRetryAfter = 300 * time.Second
+
+// State describes the maintenance mode shared by all the instances of an application.
+type State struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	// Until is the expected end of the maintenance, zero when unknown.
+	Until time.Time `json:"until,omitempty"`
+	// Allow lists the IP addresses and CIDR ranges still served during the maintenance.
+	Allow []string `json:"allow,omitempty"`
+	// Secret lets a browser bypass the maintenance by visiting /<secret> once.
+	Secret string `json:"secret,omitempty"`
+}
+
+// RetryAfter returns the delay before the expected end of the maintenance.
+func (st State) RetryAfter(now time.Time) time.Duration {
+	if st.Until.IsZero() {
+		return DefaultRetryAfter
+	}
+	if d := st.Until.Sub(now); d > time.Second {
+		return d
+	}
+	return time.Second
+}
+
+// Allows reports whether ip matches one of the addresses or ranges of st.Allow.
+func (st State) Allows(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, allowed := range st.Allow {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(allowed); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// BypassToken returns the value of the cookie set for browsers that visited /<secret>,
+// so that the secret itself is never stored in the cookie.
+func (st State) BypassToken() string {
+	sum := sha256.Sum256([]byte("socle-maintenance:" + st.Secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store persists the maintenance state.
+type Store interface {
+	Load(ctx context.Context) (State, error)
+	Save(ctx context.Context, st State) error
+}
+
+// Manager keeps an in memory copy of the state of a Store, refreshed periodically so
+// that a change made by any instance reaches all of them.
+type Manager struct {
+	Store    Store
+	ErrorLog *log.Logger
+
+	state atomic.Pointer[State]
+}
+
+// NewManager creates a manager for store, loading its current state.
+func NewManager(ctx context.Context, store Store) (*Manager, error) {
+	m := &Manager{
+		Store:    store,
+		ErrorLog: log.New(os.Stdout, "maintenance ERROR\t", log.Ldate|log.Ltime),
+	}
+	m.state.Store(&State{})
+
+	if err := m.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Current returns the last known state.
+func (m *Manager) Current() State {
+	return *m.state.Load()
+}
+
+// Set saves st and applies it to this instance immediately.
+func (m *Manager) Set(ctx context.Context, st State) error {
+	if st.Enabled && st.Since.IsZero() {
+		st.Since = time.Now()
+	}
+	if err := m.Store.Save(ctx, st); err != nil {
+		return err
+	}
+	m.state.Store(&st)
+	return nil
+}
+
+// Refresh reloads the state from the store.
+func (m *Manager) Refresh(ctx context.Context) error {
+	st, err := m.Store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	m.state.Store(&st)
+	return nil
+}
+
+// Watch refreshes the state every interval until ctx is done. It returns at once when
+// interval is not positive: the state then only changes with Set, and the instances do not
+// pick up the changes made by the others.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				m.ErrorLog.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package maintenance
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateAllows(t *testing.T) {
+	st := State{Allow: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32", "not an address"}}
+
+	tests := []struct {
+		ip    string
+		allow bool
+	}{
+		{"10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.10", true},
+		{"192.168.1.11", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"", false},
+		{"10.1.2.3:80", false},
+	}
+	for _, tt := range tests {
+		if got := st.Allows(tt.ip); got != tt.allow {
+			t.Errorf("Allows(%q) = %v, want %v", tt.ip, got, tt.allow)
+		}
+	}
+}
+
+func TestManagerWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintenance.json")
+	m, err := NewManager(context.Background(), NewFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ErrorLog = log.New(io.Discard, "", 0)
+
+	// an instance sharing the store
+	other := NewFileStore(path)
+	if err := other.Save(context.Background(), State{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		done := make(chan struct{})
+		go func() {
+			m.Watch(context.Background(), interval)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Watch(%v) did not return", interval)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Watch(ctx, 5*time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for !m.Current().Enabled {
+		if time.Now().After(deadline) {
+			t.Fatal("the state saved by another instance was not picked up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+	"github.com/socle-framework/socle/internal/sqldialect"
+)
+
+// RedisStore stores the state under a redis key.
+type RedisStore struct {
+	Pool *redis.Pool
+	Key  string
+}
+
+// NewRedisStore creates a store using the connections of pool, with keys prefixed by prefix.
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{Pool: pool, Key: prefix + "socle:maintenance"}
+}
+
+func (s *RedisStore) Load(ctx context.Context) (State, error) {
+	var st State
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return st, err
+	}
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", s.Key))
+	if errors.Is(err, redis.ErrNil) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func (s *RedisStore) Save(ctx context.Context, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("SET", s.Key, data)
+	return err
+}
+
+// BadgerStore stores the state under a badger key, for single node deployments.
+type BadgerStore struct {
+	DB  *badger.DB
+	Key []byte
+}
+
+// NewBadgerStore creates a store using db.
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{DB: db, Key: []byte("socle:maintenance")}
+}
+
+func (s *BadgerStore) Load(ctx context.Context) (State, error) {
+	var st State
+	err := s.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.Key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(data []byte) error {
+			return json.Unmarshal(data, &st)
+		})
+	})
+	return st, err
+}
+
+func (s *BadgerStore) Save(ctx context.Context, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set(s.Key, data)
+	})
+}
+
+// SQLStore stores the state in the single row table created by `socle make maintenance`.
+type SQLStore struct {
+	DB     *sql.DB
+	DBType string
+	Table  string
+}
+
+// NewSQLStore creates a store using the socle_maintenance table of db.
+func NewSQLStore(db *sql.DB, dbType string) *SQLStore {
+	return &SQLStore{DB: db, DBType: dbType, Table: "socle_maintenance"}
+}
+
+// rebind replaces the ? placeholders of query by $n ones for postgres.
+func (s *SQLStore) rebind(query string) string {
+	return sqldialect.Rebind(s.DBType, query)
+}
+
+func (s *SQLStore) Load(ctx context.Context) (State, error) {
+	var st State
+	var data string
+	query := fmt.Sprintf("SELECT state FROM %s WHERE id = 1", s.Table)
+	err := s.DB.QueryRowContext(ctx, query).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal([]byte(data), &st)
+	return st, err
+}
+
+func (s *SQLStore) Save(ctx context.Context, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	// a single statement, the instances saving their first state at once would race
+	now := time.Now()
+	query := s.rebind(fmt.Sprintf("INSERT INTO %s (id, state, created_at, updated_at) VALUES (1, ?, ?, ?) %s",
+		s.Table, sqldialect.Upsert(s.DBType, "id", "state", "updated_at")))
+	_, err = s.DB.ExecContext(ctx, query, string(data), now, now)
+	return err
+}
+
+// FileStore stores the state in a JSON file. It survives restarts but is only shared
+// by the instances running on the same host.
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore creates a store writing to path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Load(ctx context.Context) (State, error) {
+	var st State
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func (s *FileStore) Save(ctx context.Context, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+
+	// write then rename, so that readers never see a partial file
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
@@ -0,0 +1,316 @@
+// Package oauth signs users in with an external OAuth2 or OpenID Connect provider
+// (Google, GitHub, or any OIDC issuer), with the authorization code flow and PKCE.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	ErrInvalidNonce    = errors.New("id token nonce does not match")
+	ErrNoIDToken       = errors.New("no id_token in the token response")
+)
+
+// Config is the configuration of a provider. The OIDC providers only need an Issuer,
+// their endpoints being discovered; the plain OAuth2 ones need the AuthURL, TokenURL and
+// UserInfoURL. The google and github names get their defaults.
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+}
+
+// Identity is the user authenticated by a provider.
+type Identity struct {
+	Provider      string         `json:"provider"`
+	Subject       string         `json:"subject"`
+	Email         string         `json:"email,omitempty"`
+	EmailVerified bool           `json:"email_verified"`
+	Name          string         `json:"name,omitempty"`
+	Picture       string         `json:"picture,omitempty"`
+	Claims        map[string]any `json:"claims,omitempty"`
+}
+
+// Provider runs the authorization code flow with one provider.
+type Provider struct {
+	Name     string
+	OAuth2   *oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+
+	userInfoURL string
+	client      *http.Client
+}
+
+// withDefaults fills the settings of the well known providers.
+func (cfg Config) withDefaults() Config {
+	switch cfg.Name {
+	case "google":
+		if cfg.Issuer == "" {
+			cfg.Issuer = "https://accounts.google.com"
+		}
+	case "github":
+		if cfg.AuthURL == "" {
+			cfg.AuthURL = "https://github.com/login/oauth/authorize"
+		}
+		if cfg.TokenURL == "" {
+			cfg.TokenURL = "https://github.com/login/oauth/access_token"
+		}
+		if cfg.UserInfoURL == "" {
+			cfg.UserInfoURL = "https://api.github.com/user"
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"read:user", "user:email"}
+		}
+	}
+	if cfg.Issuer != "" && len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"email", "profile"}
+	}
+	return cfg
+}
+
+// NewProvider returns the provider of cfg, discovering the metadata of its issuer.
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	cfg = cfg.withDefaults()
+	p := &Provider{
+		Name: cfg.Name,
+		OAuth2: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			Endpoint:     oauth2.Endpoint{AuthURL: cfg.AuthURL, TokenURL: cfg.TokenURL},
+		},
+		userInfoURL: cfg.UserInfoURL,
+		client:      http.DefaultClient,
+	}
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		p.client = c
+	}
+
+	if cfg.Issuer == "" {
+		if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+			return nil, fmt.Errorf("oauth %s: an issuer, or the auth, token and userinfo urls are required", cfg.Name)
+		}
+		return p, nil
+	}
+
+	oidcProvider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oauth %s: %w", cfg.Name, err)
+	}
+	p.OAuth2.Endpoint = oidcProvider.Endpoint()
+	p.OAuth2.Scopes = append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	p.Verifier = oidcProvider.Verifier(&oidc.Config{ClientID: cfg.ClientID})
+	if p.userInfoURL == "" {
+		p.userInfoURL = oidcProvider.UserInfoEndpoint()
+	}
+	return p, nil
+}
+
+// IsOIDC reports whether the provider issues ID tokens.
+func (p *Provider) IsOIDC() bool {
+	return p.Verifier != nil
+}
+
+// Flow holds the random values of one authorization, kept in the session between the
+// redirection to the provider and the callback.
+type Flow struct {
+	State    string
+	Verifier string
+	Nonce    string
+}
+
+// NewFlow returns the values of a new authorization.
+func NewFlow() (Flow, error) {
+	state, err := randomString()
+	if err != nil {
+		return Flow{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return Flow{}, err
+	}
+	return Flow{State: state, Verifier: oauth2.GenerateVerifier(), Nonce: nonce}, nil
+}
+
+// AuthCodeURL returns the URL of the provider authorizing flow, with the S256 PKCE
+// challenge of its verifier.
+func (p *Provider) AuthCodeURL(flow Flow) string {
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(flow.Verifier)}
+	if p.IsOIDC() {
+		opts = append(opts, oidc.Nonce(flow.Nonce))
+	}
+	return p.OAuth2.AuthCodeURL(flow.State, opts...)
+}
+
+// Exchange exchanges the code of the callback for the tokens of the user, and returns its
+// identity: the claims of the validated ID token for OIDC providers, the userinfo of the
+// plain OAuth2 ones.
+func (p *Provider) Exchange(ctx context.Context, flow Flow, code string) (*Identity, *oauth2.Token, error) {
+	token, err := p.OAuth2.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !p.IsOIDC() {
+		id, err := p.userInfo(ctx, token)
+		return id, token, err
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, token, ErrNoIDToken
+	}
+	idToken, err := p.Verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, token, err
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, token, ErrInvalidNonce
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, token, err
+	}
+	id := identityFromClaims(p.Name, claims)
+	id.Subject = idToken.Subject
+	return id, token, nil
+}
+
+// identityFromClaims maps the standard OIDC claims, or their userinfo equivalents.
+func identityFromClaims(provider string, claims map[string]any) *Identity {
+	id := &Identity{Provider: provider, Claims: claims}
+	id.Subject = claimString(claims, "sub")
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified, _ = claims["email_verified"].(bool)
+	id.Name, _ = claims["name"].(string)
+	id.Picture, _ = claims["picture"].(string)
+	return id
+}
+
+// claimString returns the claim name, a string or a number like the GitHub user ids.
+func claimString(claims map[string]any, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return ""
+}
+
+// userInfo fetches the identity of the user of token from the userinfo endpoint.
+func (p *Provider) userInfo(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	var claims map[string]any
+	if err := p.getJSON(ctx, token, p.userInfoURL, &claims); err != nil {
+		return nil, err
+	}
+
+	id := identityFromClaims(p.Name, claims)
+	if id.Subject == "" {
+		id.Subject = claimString(claims, "id")
+	}
+	if id.Name == "" {
+		id.Name, _ = claims["login"].(string)
+	}
+	if id.Picture == "" {
+		id.Picture, _ = claims["avatar_url"].(string)
+	}
+	if p.Name == "github" {
+		// the public email of the profile is not verified, ask for the primary one
+		id.Email, id.EmailVerified = p.githubEmail(ctx, token)
+	}
+	if id.Subject == "" {
+		return nil, fmt.Errorf("oauth %s: no subject in userinfo", p.Name)
+	}
+	return id, nil
+}
+
+func (p *Provider) githubEmail(ctx context.Context, token *oauth2.Token) (string, bool) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, token, "https://api.github.com/user/emails", &emails); err != nil {
+		return "", false
+	}
+	for _, e := range emails {
+		if e.Primary {
+			return e.Email, e.Verified
+		}
+	}
+	return "", false
+}
+
+func (p *Provider) getJSON(ctx context.Context, token *oauth2.Token, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth %s: %s: %s", p.Name, url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// Providers are the providers by name.
+type Providers map[string]*Provider
+
+// NewProviders returns the providers of configs.
+func NewProviders(ctx context.Context, configs []Config) (Providers, error) {
+	providers := Providers{}
+	for _, cfg := range configs {
+		p, err := NewProvider(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers[cfg.Name] = p
+	}
+	return providers, nil
+}
+
+// Get returns the provider name.
+func (ps Providers) Get(name string) (*Provider, error) {
+	p, ok := ps[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,128 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/socle-framework/socle/internal/sqldialect"
+)
+
+var (
+	ErrNoVerifiedEmail = errors.New("the provider gave no verified email")
+	ErrInactiveUser    = errors.New("the user is not active")
+	ErrEmailTaken      = errors.New("the email belongs to a user the provider is not trusted to sign in")
+)
+
+// UserMapper maps an external identity onto the id of a local user, creating the user at
+// its first sign in.
+type UserMapper interface {
+	MapUser(ctx context.Context, id *Identity) (int, error)
+}
+
+// SQLUsers maps the identities onto the users table of socle make auth, linking them in
+// the user_identities table of socle make oauth. The inactive users are refused. An
+// identity is linked to the existing user of its email only when the provider verified it
+// and is listed in TrustEmail, so that a user signing in with Google after registering
+// keeps its account while an OIDC issuer anyone can run cannot take accounts over.
+type SQLUsers struct {
+	DB              *sql.DB
+	DBType          string
+	UsersTable      string
+	IdentitiesTable string
+	TrustEmail      map[string]bool // providers whose verified emails link to the existing users
+}
+
+// NewSQLUsers creates a mapper using the users and user_identities tables of db.
+func NewSQLUsers(db *sql.DB, dbType string) *SQLUsers {
+	return &SQLUsers{DB: db, DBType: dbType, UsersTable: "users", IdentitiesTable: "user_identities",
+		TrustEmail: map[string]bool{}}
+}
+
+// rebind replaces the ? placeholders of query by $n ones for postgres.
+func (m *SQLUsers) rebind(query string) string {
+	return sqldialect.Rebind(m.DBType, query)
+}
+
+func (m *SQLUsers) MapUser(ctx context.Context, id *Identity) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var active bool
+	query := m.rebind(fmt.Sprintf(`SELECT u.id, u.active FROM %s i JOIN %s u ON u.id = i.user_id
+		WHERE i.provider = ? AND i.subject = ?`, m.IdentitiesTable, m.UsersTable))
+	err = tx.QueryRowContext(ctx, query, id.Provider, id.Subject).Scan(&userID, &active)
+	if err == nil {
+		if !active {
+			return 0, ErrInactiveUser
+		}
+		return userID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// first sign in with this identity: link it to the user of its email, or create one
+	if id.Email == "" || !id.EmailVerified {
+		return 0, ErrNoVerifiedEmail
+	}
+	email := strings.ToLower(id.Email)
+	query = m.rebind(fmt.Sprintf(`SELECT id, active FROM %s WHERE email = ?`, m.UsersTable))
+	err = tx.QueryRowContext(ctx, query, email).Scan(&userID, &active)
+	if errors.Is(err, sql.ErrNoRows) {
+		userID, err = m.createUser(ctx, tx, id, email)
+	} else if err == nil {
+		// the user registered another way: only a trusted provider signs it in
+		if !m.TrustEmail[id.Provider] {
+			return 0, ErrEmailTaken
+		}
+		if !active {
+			return 0, ErrInactiveUser
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	query = m.rebind(fmt.Sprintf(`INSERT INTO %s (user_id, provider, subject, email, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, m.IdentitiesTable))
+	if _, err := tx.ExecContext(ctx, query, userID, id.Provider, id.Subject, email, now, now); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// createUser creates the user of id, without password: it signs in with its provider
+// until it sets one through the password reset.
+func (m *SQLUsers) createUser(ctx context.Context, tx *sql.Tx, id *Identity, email string) (int, error) {
+	first, _ := id.Claims["given_name"].(string)
+	last, _ := id.Claims["family_name"].(string)
+	if first == "" && last == "" {
+		first, last, _ = strings.Cut(id.Name, " ")
+	}
+
+	now := time.Now()
+	query := fmt.Sprintf(`INSERT INTO %s (first_name, last_name, email, password, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, m.UsersTable)
+	args := []any{first, last, email, "", true, now, now}
+
+	if sqldialect.Postgres(m.DBType) {
+		var userID int
+		err := tx.QueryRowContext(ctx, m.rebind(query+" RETURNING id"), args...).Scan(&userID)
+		return userID, err
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	userID, err := res.LastInsertId()
+	return int(userID), err
+}
@@ -0,0 +1,121 @@
+package ratelimiter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// BadgerLimiter is a fixed window limiter counting the requests in badger entries, so that
+// the limits survive the restarts of single node deployments.
+type BadgerLimiter struct {
+	DB     *badger.DB
+	Prefix string
+	// OnError is called with the errors of badger, the requests being allowed meanwhile.
+	OnError func(err error)
+
+	limit  int
+	window time.Duration
+}
+
+// NewBadgerLimiter creates a limiter of limit requests per window using db, with keys
+// prefixed by name.
+func NewBadgerLimiter(db *badger.DB, name string, limit int, window time.Duration) *BadgerLimiter {
+	return &BadgerLimiter{
+		DB:     db,
+		Prefix: "ratelimit:" + name + ":",
+		limit:  limit,
+		window: window,
+	}
+}
+
+// badgerWindow is the count of the requests of a key in the window started at start, stored
+// as two big endian integers.
+type badgerWindow struct {
+	count int64
+	start time.Time
+}
+
+func (w badgerWindow) encode() []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(w.count))
+	binary.BigEndian.PutUint64(b[8:], uint64(w.start.UnixNano()))
+	return b
+}
+
+// current returns the window of key, a new one when it has ended.
+func (rl *BadgerLimiter) current(txn *badger.Txn, key string, now time.Time) (badgerWindow, error) {
+	item, err := txn.Get([]byte(rl.Prefix + key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return badgerWindow{start: now}, nil
+	}
+	if err != nil {
+		return badgerWindow{}, err
+	}
+
+	var w badgerWindow
+	err = item.Value(func(val []byte) error {
+		if len(val) != 16 {
+			return nil
+		}
+		w.count = int64(binary.BigEndian.Uint64(val))
+		w.start = time.Unix(0, int64(binary.BigEndian.Uint64(val[8:])))
+		return nil
+	})
+	if err != nil || now.Sub(w.start) >= rl.window {
+		return badgerWindow{start: now}, err
+	}
+	return w, nil
+}
+
+func (rl *BadgerLimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := time.Now()
+	err := rl.update(func(txn *badger.Txn) error {
+		w, err := rl.current(txn, key, now)
+		if err != nil {
+			return err
+		}
+		res.Reset = rl.window - now.Sub(w.start)
+		res.Allowed = w.count < int64(rl.limit)
+		if !res.Allowed {
+			res.RetryAfter = res.Reset
+			return nil
+		}
+		w.count++
+		res.Remaining = rl.limit - int(w.count)
+		// badger expires the entries to the second
+		return txn.SetEntry(badger.NewEntry([]byte(rl.Prefix+key), w.encode()).WithTTL(res.Reset + time.Second))
+	})
+	if err != nil {
+		rl.failed(err)
+		return Result{Allowed: true, Limit: rl.limit, Remaining: rl.limit, Reset: rl.window}
+	}
+	return res
+}
+
+// badgerRetries is the number of times a transaction conflicting with another one is run
+// again, before the request is allowed through OnError.
+const badgerRetries = 5
+
+// update runs fn in a read-write transaction, retrying when it conflicts with another one
+// after a random backoff, so that the transactions of a hot key spread out.
+func (rl *BadgerLimiter) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for attempt := range badgerRetries {
+		if err = rl.DB.Update(fn); !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+		time.Sleep(rand.N(time.Duration(attempt+1) * time.Millisecond))
+	}
+	return err
+}
+
+func (rl *BadgerLimiter) failed(err error) {
+	if rl.OnError != nil {
+		rl.OnError(err)
+	}
+}
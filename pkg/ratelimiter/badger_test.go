@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+func newBadgerDB(t *testing.T) *badger.DB {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBadgerLimiter(t *testing.T) {
+	db := newBadgerDB(t)
+	window := 100 * time.Millisecond
+	rl := NewBadgerLimiter(db, "login", 2, window)
+	other := NewBadgerLimiter(db, "api", 2, window)
+
+	steps := []struct {
+		limiter   *BadgerLimiter
+		key       string
+		sleep     time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{limiter: rl, key: "ip:1", allowed: true, remaining: 1},
+		{limiter: rl, key: "ip:1", allowed: true, remaining: 0},
+		{limiter: rl, key: "ip:1", allowed: false, remaining: 0},
+		{limiter: rl, key: "ip:2", allowed: true, remaining: 1},
+		// the limiters are kept apart by name
+		{limiter: other, key: "ip:1", allowed: true, remaining: 1},
+		// the window rolls over
+		{limiter: rl, key: "ip:1", sleep: window, allowed: true, remaining: 1},
+	}
+	for i, st := range steps {
+		time.Sleep(st.sleep)
+		res := st.limiter.Allow(st.key)
+		if res.Allowed != st.allowed || res.Remaining != st.remaining || res.Limit != 2 {
+			t.Fatalf("%d: Allow(%s) = %+v, want allowed %v, remaining %d", i, st.key, res, st.allowed, st.remaining)
+		}
+		if res.Allowed && res.RetryAfter != 0 || !res.Allowed && res.RetryAfter <= 0 {
+			t.Errorf("%d: RetryAfter = %v with allowed %v", i, res.RetryAfter, res.Allowed)
+		}
+		if res.Reset <= 0 || res.Reset > window {
+			t.Errorf("%d: Reset = %v", i, res.Reset)
+		}
+	}
+}
+
+func TestBadgerLimiterContention(t *testing.T) {
+	const limit, requests = 20, 100
+	rl := NewBadgerLimiter(newBadgerDB(t), "login", limit, time.Minute)
+	var mu sync.Mutex
+	var errs int
+	rl.OnError = func(err error) {
+		mu.Lock()
+		errs++
+		mu.Unlock()
+	}
+
+	var allowed, refused int
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := rl.Allow("ip:1")
+			mu.Lock()
+			defer mu.Unlock()
+			if res.Allowed {
+				allowed++
+			} else {
+				refused++
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the conflicting transactions are retried without end")
+	}
+
+	// the requests allowed through OnError are not counted
+	if counted := allowed - errs; counted > limit || allowed+refused != requests {
+		t.Errorf("allowed %d (%d on errors), refused %d, want at most %d counted", allowed, errs, refused, limit)
+	}
+	if refused > 0 && allowed-errs != limit {
+		t.Errorf("refused %d requests with %d counted, want the limit %d reached", refused, allowed-errs, limit)
+	}
+}
+
+func TestBadgerLimiterOnError(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := NewBadgerLimiter(db, "login", 1, time.Minute)
+	var errs []error
+	rl.OnError = func(err error) { errs = append(errs, err) }
+
+	db.Close()
+	res := rl.Allow("ip:1")
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("Allow with badger closed = %+v, want the request allowed", res)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Errorf("OnError errors = %v", errs)
+	}
+}
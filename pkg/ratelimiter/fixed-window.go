@@ -1,47 +1,53 @@
 package ratelimiter
 
 import (
-	"sync"
 	"time"
 )
 
+// FixedWindowRateLimiter allows limit requests per window, the window of a key starting
+// with its first request. A client may send up to twice the limit around the end of a
+// window: the sliding window limiters smooth these bursts out.
 type FixedWindowRateLimiter struct {
-	sync.RWMutex
-	clients map[string]int
-	limit   int
-	window  time.Duration
+	store  *memoryStore[fixedWindow]
+	limit  int
+	window time.Duration
 }
 
-func NewFixedWindowLimiter(limit int, window time.Duration) *FixedWindowRateLimiter {
+// fixedWindow is the count of the requests of a client in its current window.
+type fixedWindow struct {
+	count int
+	start time.Time
+}
+
+func NewFixedWindowLimiter(limit int, window time.Duration, opts ...Option) *FixedWindowRateLimiter {
 	return &FixedWindowRateLimiter{
-		clients: make(map[string]int),
-		limit:   limit,
-		window:  window,
+		store:  newMemoryStore[fixedWindow](newOptions(window, opts)),
+		limit:  limit,
+		window: window,
 	}
 }
 
-func (rl *FixedWindowRateLimiter) Allow(ip string) (bool, time.Duration) {
-	rl.RLock()
-	count, exists := rl.clients[ip]
-	rl.RUnlock()
-
-	if !exists || count < rl.limit {
-		rl.Lock()
-		if !exists {
-			go rl.resetCount(ip)
+func (rl *FixedWindowRateLimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := rl.store.now()
+	rl.store.update(key, now, func(w *fixedWindow) time.Time {
+		if w.start.IsZero() {
+			w.start = now
 		}
-
-		rl.clients[ip]++
-		rl.Unlock()
-		return true, 0
-	}
-
-	return false, rl.window
+		res.Reset = rl.window - now.Sub(w.start)
+		if w.count < rl.limit {
+			w.count++
+			res.Allowed = true
+		} else {
+			res.RetryAfter = res.Reset
+		}
+		res.Remaining = rl.limit - w.count
+		return w.start.Add(rl.window)
+	})
+	return res
 }
 
-func (rl *FixedWindowRateLimiter) resetCount(ip string) {
-	time.Sleep(rl.window)
-	rl.Lock()
-	delete(rl.clients, ip)
-	rl.Unlock()
+// Stop stops the goroutine removing the expired keys.
+func (rl *FixedWindowRateLimiter) Stop() {
+	rl.store.close()
 }
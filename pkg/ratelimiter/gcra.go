@@ -0,0 +1,55 @@
+package ratelimiter
+
+import (
+	"time"
+)
+
+// GCRALimiter applies the generic cell rate algorithm: the requests of a key are spaced by
+// window/limit on average, with bursts of up to limit requests. It behaves like a token
+// bucket but keeps a single time per key, the theoretical arrival time of its next request.
+type GCRALimiter struct {
+	store    *memoryStore[time.Time]
+	limit    int
+	interval time.Duration // between two requests at the sustained rate
+	burst    time.Duration // tolerance, the time for limit requests
+}
+
+func NewGCRALimiter(limit int, window time.Duration, opts ...Option) *GCRALimiter {
+	return &GCRALimiter{
+		store:    newMemoryStore[time.Time](newOptions(window, opts)),
+		limit:    limit,
+		interval: window / time.Duration(limit),
+		burst:    window,
+	}
+}
+
+func (rl *GCRALimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := rl.store.now()
+	rl.store.update(key, now, func(tat *time.Time) time.Time {
+		t := *tat
+		if t.Before(now) {
+			t = now
+		}
+
+		next := t.Add(rl.interval)
+		if allowAt := next.Add(-rl.burst); now.Before(allowAt) {
+			res.RetryAfter = allowAt.Sub(now)
+			next = t
+		} else {
+			*tat = next
+			res.Allowed = true
+		}
+
+		// the requests left are the intervals between next and the end of the burst
+		res.Remaining = int((rl.burst - next.Sub(now)) / rl.interval)
+		res.Reset = next.Sub(now)
+		return next
+	})
+	return res
+}
+
+// Stop stops the goroutine removing the expired keys.
+func (rl *GCRALimiter) Stop() {
+	rl.store.close()
+}
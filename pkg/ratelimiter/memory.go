@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps the state of the keys of a memory limiter, at most maxKeys of them:
+// the least recently used key is evicted to make room for a new one. A single janitor
+// goroutine removes the keys once expired.
+type memoryStore[T any] struct {
+	mu      sync.Mutex
+	keys    map[string]*list.Element
+	lru     *list.List // front is the most recently used
+	maxKeys int
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type memoryEntry[T any] struct {
+	key     string
+	state   T
+	expires time.Time
+}
+
+func newMemoryStore[T any](o options) *memoryStore[T] {
+	s := &memoryStore[T]{
+		keys:    make(map[string]*list.Element),
+		lru:     list.New(),
+		maxKeys: o.maxKeys,
+		now:     o.now,
+		stop:    make(chan struct{}),
+	}
+	go s.janitor(o.cleanup)
+	return s
+}
+
+// update calls fn with the state of key, the zero state for a new or expired key, and
+// keeps it until the expiry returned by fn, excluded.
+func (s *memoryStore[T]) update(key string, now time.Time, fn func(state *T) (expires time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.keys[key]
+	if ok && !now.Before(el.Value.(*memoryEntry[T]).expires) {
+		s.remove(el)
+		ok = false
+	}
+	if !ok {
+		if s.lru.Len() >= s.maxKeys {
+			s.remove(s.lru.Back())
+		}
+		el = s.lru.PushFront(&memoryEntry[T]{key: key})
+		s.keys[key] = el
+	} else {
+		s.lru.MoveToFront(el)
+	}
+
+	e := el.Value.(*memoryEntry[T])
+	e.expires = fn(&e.state)
+}
+
+func (s *memoryStore[T]) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.keys, el.Value.(*memoryEntry[T]).key)
+}
+
+func (s *memoryStore[T]) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			now := s.now()
+			s.mu.Lock()
+			for el := s.lru.Back(); el != nil; {
+				prev := el.Prev()
+				if !now.Before(el.Value.(*memoryEntry[T]).expires) {
+					s.remove(el)
+				}
+				el = prev
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// close stops the janitor.
+func (s *memoryStore[T]) close() {
+	s.once.Do(func() { close(s.stop) })
+}
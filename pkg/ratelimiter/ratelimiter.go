@@ -2,12 +2,89 @@ package ratelimiter
 
 import "time"
 
+// Limiter counts the requests of the clients, told apart by key.
 type Limiter interface {
-	Allow(ip string) (bool, time.Duration)
+	// Allow counts a request of key and reports whether it is within the limit.
+	Allow(key string) Result
+}
+
+// Result is the decision of a limiter on a request, with the state of the quota of its key
+// for the RateLimit headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota of the key is full again.
+	Reset time.Duration
+	// RetryAfter is the time until a request of the key is allowed again, 0 when allowed.
+	RetryAfter time.Duration
 }
 
 type Config struct {
 	RequestsPerTimeFrame int
 	TimeFrame            time.Duration
 	Enabled              bool
+	Store                string // memory, redis or badger, defaults to the cache of the app
+	Algorithm            string // fixed_window (default), sliding_window, sliding_log, token_bucket or gcra
+	MaxKeys              int    // keys kept by the memory limiters, the least recently used are evicted
+}
+
+// Algorithms are the algorithms of New.
+var Algorithms = []string{"fixed_window", "sliding_window", "sliding_log", "token_bucket", "gcra"}
+
+// New returns the memory limiter of algorithm, allowing limit requests per window.
+func New(algorithm string, limit int, window time.Duration, opts ...Option) (Limiter, bool) {
+	switch algorithm {
+	case "", "fixed_window":
+		return NewFixedWindowLimiter(limit, window, opts...), true
+	case "sliding_window":
+		return NewSlidingWindowLimiter(limit, window, opts...), true
+	case "sliding_log":
+		return NewSlidingLogLimiter(limit, window, opts...), true
+	case "token_bucket":
+		return NewTokenBucketLimiter(limit, window, opts...), true
+	case "gcra":
+		return NewGCRALimiter(limit, window, opts...), true
+	}
+	return nil, false
+}
+
+// Option configures the memory limiters.
+type Option func(*options)
+
+type options struct {
+	maxKeys int
+	cleanup time.Duration
+	now     func() time.Time // the clock of the limiter, set by the tests
+}
+
+// WithMaxKeys bounds the keys kept by a limiter to n, evicting the least recently used
+// ones beyond. The default is 100000.
+func WithMaxKeys(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxKeys = n
+		}
+	}
+}
+
+// WithCleanupInterval sets how often the expired keys are removed, by default every window
+// but at most every minute.
+func WithCleanupInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.cleanup = d
+		}
+	}
+}
+
+func newOptions(window time.Duration, opts []Option) options {
+	o := options{maxKeys: 100000, cleanup: min(window, time.Minute), now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cleanup <= 0 {
+		o.cleanup = time.Minute
+	}
+	return o
 }
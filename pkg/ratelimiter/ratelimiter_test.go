@@ -0,0 +1,161 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is the time of a test, moved forward by the steps.
+type clock struct {
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) option() Option {
+	return func(o *options) { o.now = func() time.Time { return c.now } }
+}
+
+// step is a request sent at the offset at, from the start of the test.
+type step struct {
+	at         time.Duration
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+func TestLimiters(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		limit     int
+		window    time.Duration
+		steps     []step
+	}{
+		{
+			algorithm: "fixed_window", limit: 3, window: time.Minute,
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2, reset: time.Minute},
+				{at: 10 * time.Second, allowed: true, remaining: 1, reset: 50 * time.Second},
+				{at: 20 * time.Second, allowed: true, remaining: 0, reset: 40 * time.Second},
+				{at: 30 * time.Second, remaining: 0, reset: 30 * time.Second, retryAfter: 30 * time.Second},
+				{at: 59 * time.Second, remaining: 0, reset: time.Second, retryAfter: time.Second},
+				// the window of the key ends a minute after its first request
+				{at: time.Minute, allowed: true, remaining: 2, reset: time.Minute},
+			},
+		},
+		{
+			algorithm: "sliding_window", limit: 3, window: time.Minute,
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2, reset: 2 * time.Minute},
+				{at: 0, allowed: true, remaining: 1, reset: 2 * time.Minute},
+				{at: 0, allowed: true, remaining: 0, reset: 2 * time.Minute},
+				{at: 0, remaining: 0, reset: 2 * time.Minute, retryAfter: time.Minute},
+				// half of the previous window overlaps the sliding one: 1.5 requests
+				{at: 90 * time.Second, allowed: true, remaining: 1, reset: 90 * time.Second},
+				{at: 90 * time.Second, allowed: true, remaining: 0, reset: 90 * time.Second},
+				// the previous window weighs less than a request after 40s
+				{at: 90 * time.Second, remaining: 0, reset: 90 * time.Second, retryAfter: 10 * time.Second},
+				{at: 101 * time.Second, allowed: true, remaining: 0, reset: 79 * time.Second},
+				// no request in the previous window
+				{at: 200 * time.Second, allowed: true, remaining: 2, reset: 100 * time.Second},
+			},
+		},
+		{
+			algorithm: "sliding_log", limit: 3, window: time.Minute,
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2, reset: time.Minute},
+				{at: 10 * time.Second, allowed: true, remaining: 1, reset: time.Minute},
+				{at: 20 * time.Second, allowed: true, remaining: 0, reset: time.Minute},
+				{at: 30 * time.Second, remaining: 0, reset: 50 * time.Second, retryAfter: 30 * time.Second},
+				// the first request leaves the window
+				{at: time.Minute, allowed: true, remaining: 0, reset: time.Minute},
+				{at: 65 * time.Second, remaining: 0, reset: 55 * time.Second, retryAfter: 5 * time.Second},
+				{at: 70 * time.Second, allowed: true, remaining: 0, reset: time.Minute},
+			},
+		},
+		{
+			algorithm: "token_bucket", limit: 3, window: 3 * time.Second,
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2, reset: time.Second},
+				{at: 0, allowed: true, remaining: 1, reset: 2 * time.Second},
+				{at: 0, allowed: true, remaining: 0, reset: 3 * time.Second},
+				{at: 0, remaining: 0, reset: 3 * time.Second, retryAfter: time.Second},
+				// half a token refilled
+				{at: 500 * time.Millisecond, remaining: 0, reset: 2500 * time.Millisecond, retryAfter: 500 * time.Millisecond},
+				{at: time.Second, allowed: true, remaining: 0, reset: 3 * time.Second},
+				// the bucket is full again
+				{at: 10 * time.Second, allowed: true, remaining: 2, reset: time.Second},
+			},
+		},
+		{
+			algorithm: "gcra", limit: 3, window: 3 * time.Second,
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2, reset: time.Second},
+				{at: 0, allowed: true, remaining: 1, reset: 2 * time.Second},
+				{at: 0, allowed: true, remaining: 0, reset: 3 * time.Second},
+				{at: 0, remaining: 0, reset: 3 * time.Second, retryAfter: time.Second},
+				// one interval later
+				{at: time.Second, allowed: true, remaining: 0, reset: 3 * time.Second},
+				{at: 10 * time.Second, allowed: true, remaining: 2, reset: time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			c := newClock()
+			start := c.now
+			l, ok := New(tt.algorithm, tt.limit, tt.window, c.option())
+			if !ok {
+				t.Fatalf("unknown algorithm %s", tt.algorithm)
+			}
+			defer l.(interface{ Stop() }).Stop()
+			for i, st := range tt.steps {
+				c.now = start.Add(st.at)
+				got := l.Allow("ip:1")
+				want := Result{Allowed: st.allowed, Limit: tt.limit, Remaining: st.remaining, Reset: st.reset, RetryAfter: st.retryAfter}
+				if got != want {
+					t.Errorf("%d: Allow at %v = %+v, want %+v", i, st.at, got, want)
+				}
+			}
+
+			// the keys do not share their quota
+			if res := l.Allow("ip:2"); !res.Allowed || res.Remaining != tt.limit-1 {
+				t.Errorf("Allow of another key = %+v, want a full quota", res)
+			}
+		})
+	}
+}
+
+func TestNewUnknownAlgorithm(t *testing.T) {
+	if l, ok := New("leaky_bucket", 1, time.Minute); ok || l != nil {
+		t.Errorf("New(leaky_bucket) = %v, %v, want no limiter", l, ok)
+	}
+	for _, algorithm := range append(Algorithms, "") {
+		if _, ok := New(algorithm, 1, time.Minute); !ok {
+			t.Errorf("New(%q) failed", algorithm)
+		}
+	}
+}
+
+func TestMemoryStoreMaxKeys(t *testing.T) {
+	c := newClock()
+	rl := NewFixedWindowLimiter(1, time.Minute, WithMaxKeys(2), c.option())
+	defer rl.Stop()
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		rl.Allow(key)
+	}
+	// b is the least recently used key when c comes in
+	if got := len(rl.store.keys); got != 2 {
+		t.Fatalf("%d keys kept, want 2", got)
+	}
+	if !rl.Allow("b").Allowed {
+		t.Error("evicted key b still limited")
+	}
+	if rl.Allow("c").Allowed {
+		t.Error("key c lost its count")
+	}
+}
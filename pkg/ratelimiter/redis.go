@@ -0,0 +1,71 @@
+package ratelimiter
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisLimiter is a fixed window limiter counting the requests in redis keys, so that the
+// replicas of an application share their limits.
+type RedisLimiter struct {
+	Pool   *redis.Pool
+	Prefix string
+	// OnError is called with the errors of redis, the requests being allowed meanwhile.
+	OnError func(err error)
+
+	limit  int
+	window time.Duration
+}
+
+// NewRedisLimiter creates a limiter of limit requests per window using the connections of
+// pool, with keys prefixed by prefix and name.
+func NewRedisLimiter(pool *redis.Pool, prefix, name string, limit int, window time.Duration) *RedisLimiter {
+	return &RedisLimiter{
+		Pool:   pool,
+		Prefix: prefix + "socle:ratelimit:" + name + ":",
+		limit:  limit,
+		window: window,
+	}
+}
+
+// allowScript counts a request of KEYS[1], starting its window of ARGV[1] milliseconds with
+// the first one, and returns the count and the milliseconds left in the window.
+var allowScript = redis.NewScript(1, `
+local n = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if n == 1 or ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {n, ttl}
+`)
+
+func (rl *RedisLimiter) Allow(key string) Result {
+	conn := rl.Pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64s(allowScript.Do(conn, rl.Prefix+key, rl.window.Milliseconds()))
+	if err != nil || len(values) != 2 {
+		rl.failed(err)
+		return Result{Allowed: true, Limit: rl.limit, Remaining: rl.limit, Reset: rl.window}
+	}
+
+	count, reset := int(values[0]), time.Duration(values[1])*time.Millisecond
+	res := Result{
+		Allowed:   count <= rl.limit,
+		Limit:     rl.limit,
+		Remaining: max(rl.limit-count, 0),
+		Reset:     reset,
+	}
+	if !res.Allowed {
+		res.RetryAfter = reset
+	}
+	return res
+}
+
+func (rl *RedisLimiter) failed(err error) {
+	if err != nil && rl.OnError != nil {
+		rl.OnError(err)
+	}
+}
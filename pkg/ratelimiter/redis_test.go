@@ -0,0 +1,102 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func newRedisPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) { return redis.Dial("tcp", addr) },
+	}
+	t.Cleanup(func() { pool.Close() })
+	return mr, pool
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr, pool := newRedisPool(t)
+	rl := NewRedisLimiter(pool, "app:", "login", 2, time.Minute)
+
+	steps := []struct {
+		key       string
+		forward   time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{key: "ip:1", allowed: true, remaining: 1},
+		{key: "ip:1", allowed: true, remaining: 0},
+		{key: "ip:1", allowed: false, remaining: 0},
+		{key: "ip:2", allowed: true, remaining: 1},
+		// still in the window started by the first request
+		{key: "ip:1", forward: 59 * time.Second, allowed: false, remaining: 0},
+		// the window rolls over
+		{key: "ip:1", forward: 2 * time.Second, allowed: true, remaining: 1},
+	}
+	for i, st := range steps {
+		mr.FastForward(st.forward)
+		res := rl.Allow(st.key)
+		if res.Allowed != st.allowed || res.Remaining != st.remaining || res.Limit != 2 {
+			t.Fatalf("%d: Allow(%s) = %+v, want allowed %v, remaining %d", i, st.key, res, st.allowed, st.remaining)
+		}
+		if res.Allowed && res.RetryAfter != 0 || !res.Allowed && res.RetryAfter <= 0 {
+			t.Errorf("%d: RetryAfter = %v with allowed %v", i, res.RetryAfter, res.Allowed)
+		}
+		if res.Reset <= 0 || res.Reset > time.Minute {
+			t.Errorf("%d: Reset = %v", i, res.Reset)
+		}
+	}
+
+	// the counts are kept under the prefix and the name of the limiter, until their window ends
+	key := "app:socle:ratelimit:login:ip:1"
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("%s: TTL = %v, want the window", key, ttl)
+	}
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != key {
+		t.Errorf("keys = %v, want [%s]", keys, key)
+	}
+}
+
+func TestRedisLimiterNamesApart(t *testing.T) {
+	_, pool := newRedisPool(t)
+	login := NewRedisLimiter(pool, "", "login", 1, time.Minute)
+	api := NewRedisLimiter(pool, "", "api", 1, time.Minute)
+
+	if !login.Allow("ip:1").Allowed || login.Allow("ip:1").Allowed {
+		t.Fatal("login limiter does not count")
+	}
+	if !api.Allow("ip:1").Allowed {
+		t.Error("the limiters share their counts")
+	}
+}
+
+func TestRedisLimiterOnError(t *testing.T) {
+	mr, pool := newRedisPool(t)
+	rl := NewRedisLimiter(pool, "", "login", 1, time.Minute)
+	var errs []error
+	rl.OnError = func(err error) { errs = append(errs, err) }
+
+	rl.Allow("ip:1")
+	mr.SetError("LOADING redis is loading")
+	res := rl.Allow("ip:1")
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("Allow with redis failing = %+v, want the request allowed", res)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("OnError called %d times, want 1", len(errs))
+	}
+
+	mr.SetError("")
+	mr.Close()
+	if res := rl.Allow("ip:1"); !res.Allowed {
+		t.Errorf("Allow with redis down = %+v, want the request allowed", res)
+	}
+	if len(errs) != 2 || errs[1] == nil {
+		t.Errorf("OnError errors = %v", errs)
+	}
+}
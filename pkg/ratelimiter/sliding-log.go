@@ -0,0 +1,56 @@
+package ratelimiter
+
+import (
+	"time"
+)
+
+// SlidingLogLimiter allows limit requests per sliding window exactly, keeping the time of
+// the requests of each key within the last window: up to limit times per key.
+type SlidingLogLimiter struct {
+	store  *memoryStore[[]time.Time]
+	limit  int
+	window time.Duration
+}
+
+func NewSlidingLogLimiter(limit int, window time.Duration, opts ...Option) *SlidingLogLimiter {
+	return &SlidingLogLimiter{
+		store:  newMemoryStore[[]time.Time](newOptions(window, opts)),
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (rl *SlidingLogLimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := rl.store.now()
+	rl.store.update(key, now, func(log *[]time.Time) time.Time {
+		// drop the requests out of the window, the oldest first
+		times := *log
+		i := 0
+		for i < len(times) && now.Sub(times[i]) >= rl.window {
+			i++
+		}
+		times = times[i:]
+
+		if len(times) < rl.limit {
+			times = append(times, now)
+			res.Allowed = true
+		} else {
+			res.RetryAfter = times[0].Add(rl.window).Sub(now)
+		}
+		*log = times
+
+		res.Remaining = rl.limit - len(times)
+		if len(times) == 0 {
+			return now
+		}
+		res.Reset = times[len(times)-1].Add(rl.window).Sub(now)
+		return times[len(times)-1].Add(rl.window)
+	})
+	return res
+}
+
+// Stop stops the goroutine removing the expired keys.
+func (rl *SlidingLogLimiter) Stop() {
+	rl.store.close()
+}
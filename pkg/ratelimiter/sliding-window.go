@@ -0,0 +1,89 @@
+package ratelimiter
+
+import (
+	"time"
+)
+
+// SlidingWindowLimiter allows limit requests per sliding window, estimating the requests
+// of the last window from the counts of the current and previous fixed windows, the
+// previous one weighted by its overlap with the sliding window. It keeps two counters per
+// key, at the price of some approximation.
+type SlidingWindowLimiter struct {
+	store  *memoryStore[slidingWindow]
+	limit  int
+	window time.Duration
+}
+
+type slidingWindow struct {
+	start    time.Time // of the current fixed window
+	current  int
+	previous int
+}
+
+func NewSlidingWindowLimiter(limit int, window time.Duration, opts ...Option) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		store:  newMemoryStore[slidingWindow](newOptions(window, opts)),
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (rl *SlidingWindowLimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := rl.store.now()
+	rl.store.update(key, now, func(w *slidingWindow) time.Time {
+		// move the fixed windows forward to the one of now
+		if w.start.IsZero() {
+			w.start = now.Truncate(rl.window)
+		}
+		if elapsed := now.Sub(w.start); elapsed >= rl.window {
+			if elapsed < 2*rl.window {
+				w.previous = w.current
+			} else {
+				w.previous = 0
+			}
+			w.current = 0
+			w.start = now.Truncate(rl.window)
+		}
+
+		elapsed := now.Sub(w.start)
+		weight := 1 - float64(elapsed)/float64(rl.window)
+		count := int(float64(w.previous)*weight) + w.current
+		if count < rl.limit {
+			w.current++
+			count++
+			res.Allowed = true
+		}
+		res.Remaining = max(rl.limit-count, 0)
+		// the current window weighs on the next one, the previous one until the end of
+		// the current one
+		switch {
+		case w.current > 0:
+			res.Reset = 2*rl.window - elapsed
+		case w.previous > 0:
+			res.Reset = rl.window - elapsed
+		}
+		if !res.Allowed {
+			res.RetryAfter = rl.retryAfter(w, elapsed)
+		}
+		return w.start.Add(2 * rl.window)
+	})
+	return res
+}
+
+// retryAfter returns the time until the weight of the previous window lets a request in,
+// or the end of the current window when its own count is at the limit.
+func (rl *SlidingWindowLimiter) retryAfter(w *slidingWindow, elapsed time.Duration) time.Duration {
+	if w.current >= rl.limit || w.previous == 0 {
+		return rl.window - elapsed
+	}
+	// smallest elapsed time with previous * (1 - elapsed/window) + current < limit
+	free := float64(rl.limit-w.current) / float64(w.previous)
+	at := time.Duration((1 - free) * float64(rl.window))
+	return max(at-elapsed, time.Millisecond)
+}
+
+// Stop stops the goroutine removing the expired keys.
+func (rl *SlidingWindowLimiter) Stop() {
+	rl.store.close()
+}
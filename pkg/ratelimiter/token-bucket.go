@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+// TokenBucketLimiter gives each key a bucket of limit tokens, refilled at limit tokens per
+// window. A request takes a token: a client may burst up to limit requests, then sends
+// them at the refill rate.
+type TokenBucketLimiter struct {
+	store  *memoryStore[tokenBucket]
+	limit  int
+	window time.Duration
+	rate   float64 // tokens per second
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucketLimiter(limit int, window time.Duration, opts ...Option) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		store:  newMemoryStore[tokenBucket](newOptions(window, opts)),
+		limit:  limit,
+		window: window,
+		rate:   float64(limit) / window.Seconds(),
+	}
+}
+
+func (rl *TokenBucketLimiter) Allow(key string) Result {
+	res := Result{Limit: rl.limit}
+	now := rl.store.now()
+	rl.store.update(key, now, func(b *tokenBucket) time.Time {
+		if b.last.IsZero() {
+			b.tokens = float64(rl.limit)
+		} else {
+			b.tokens = math.Min(float64(rl.limit), b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			res.Allowed = true
+		} else {
+			res.RetryAfter = rl.refill(1 - b.tokens)
+		}
+		res.Remaining = int(b.tokens)
+		res.Reset = rl.refill(float64(rl.limit) - b.tokens)
+		// a full bucket is the state of a new key
+		return now.Add(res.Reset)
+	})
+	return res
+}
+
+// refill returns the time to refill tokens.
+func (rl *TokenBucketLimiter) refill(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / rl.rate * float64(time.Second)))
+}
+
+// Stop stops the goroutine removing the expired keys.
+func (rl *TokenBucketLimiter) Stop() {
+	rl.store.close()
+}
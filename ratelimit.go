@@ -0,0 +1,235 @@
+package socle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/ratelimiter"
+)
+
+// rateLimitRule is the limit of the routes under path, declared in the rate_limit groups
+// of socle.yaml.
+type rateLimitRule struct {
+	path    string
+	key     string
+	limiter ratelimiter.Limiter
+}
+
+// initRateLimiter creates the RateLimiter of the rate_limit middleware and gRPC interceptor
+// when RATE_LIMITER_ENABLED is set, and the limiters of the route groups of socle.yaml.
+// The groups are limited even when the default limiter is disabled.
+func (s *Socle) initRateLimiter() error {
+	cfg := s.env.rateLimiter
+	if cfg.Enabled {
+		if cfg.RequestsPerTimeFrame <= 0 || cfg.TimeFrame <= 0 {
+			return fmt.Errorf("rate limiter: RATE_LIMITER_REQUESTS_COUNT and RATE_LIMITER_TIME must be positive")
+		}
+		limiter, err := s.newLimiter("default", cfg.RequestsPerTimeFrame, cfg.TimeFrame)
+		if err != nil {
+			return err
+		}
+		s.RateLimiter = limiter
+	}
+
+	rl := s.appConfig.RateLimit
+	if !validRateLimitKey(rl.Key) {
+		return fmt.Errorf("rate limiter: unknown key %q, expected ip, api_key, subject or auto", rl.Key)
+	}
+	for _, g := range rl.Groups {
+		if g.Path == "" || g.Requests <= 0 || g.Window <= 0 {
+			return fmt.Errorf("rate limiter: group %q needs a path, and positive requests and window", g.Path)
+		}
+		if !validRateLimitKey(g.Key) {
+			return fmt.Errorf("rate limiter: group %q: unknown key %q, expected ip, api_key, subject or auto", g.Path, g.Key)
+		}
+		key := g.Key
+		if key == "" {
+			key = rl.Key
+		}
+		path := "/" + strings.Trim(g.Path, "/")
+		limiter, err := s.newLimiter(path, g.Requests, g.Window)
+		if err != nil {
+			return err
+		}
+		s.rateLimits = append(s.rateLimits, rateLimitRule{
+			path:    path,
+			key:     key,
+			limiter: limiter,
+		})
+	}
+	// the most specific group wins
+	slices.SortStableFunc(s.rateLimits, func(a, b rateLimitRule) int {
+		return len(b.path) - len(a.path)
+	})
+	return nil
+}
+
+// newLimiter returns a limiter of limit requests per window, counting in the store chosen
+// by RATE_LIMITER_STORE. When RATE_LIMITER_STORE is empty, the counts are shared through
+// redis or badger if a cache is configured, or kept in memory otherwise. The counts of
+// the limiters are kept apart by name. The redis and badger stores count fixed windows,
+// the other RATE_LIMITER_ALGORITHM are kept in memory.
+func (s *Socle) newLimiter(name string, limit int, window time.Duration) (ratelimiter.Limiter, error) {
+	cfg := s.env.rateLimiter
+	fixed := cfg.Algorithm == "" || cfg.Algorithm == "fixed_window"
+	kind := cfg.Store
+	if kind == "" {
+		switch {
+		case !fixed:
+			kind = "memory"
+		case redisPool != nil:
+			kind = "redis"
+		case badgerConn != nil:
+			kind = "badger"
+		default:
+			kind = "memory"
+		}
+	}
+
+	onError := func(err error) {
+		s.Log.ErrorLog.Println("rate limiter:", name+":", err)
+	}
+	if kind != "memory" && !fixed {
+		return nil, fmt.Errorf("rate limiter: the %s store only counts fixed windows, not %s", kind, cfg.Algorithm)
+	}
+
+	switch kind {
+	case "redis":
+		if redisPool == nil {
+			redisPool = s.createRedisPool()
+		}
+		l := ratelimiter.NewRedisLimiter(redisPool, s.redisPrefix(), name, limit, window)
+		l.OnError = onError
+		return l, nil
+	case "badger":
+		if badgerConn == nil {
+			badgerConn = s.createBadgerConn()
+		}
+		if badgerConn == nil {
+			return nil, errors.New("rate limiter: unable to open badger database")
+		}
+		l := ratelimiter.NewBadgerLimiter(badgerConn, name, limit, window)
+		l.OnError = onError
+		return l, nil
+	case "memory":
+		l, ok := ratelimiter.New(cfg.Algorithm, limit, window, ratelimiter.WithMaxKeys(cfg.MaxKeys))
+		if !ok {
+			return nil, fmt.Errorf("rate limiter: unknown algorithm %s, expected one of %s", cfg.Algorithm, strings.Join(ratelimiter.Algorithms, ", "))
+		}
+		return l, nil
+	}
+	return nil, errors.New("rate limiter: unknown store " + kind)
+}
+
+func validRateLimitKey(key string) bool {
+	return slices.Contains([]string{"", "auto", "ip", "api_key", "subject"}, key)
+}
+
+// RateLimitMiddleware limits the requests of each client with the limiter of the most
+// specific rate_limit group of socle.yaml matching the path, or with the RateLimiter.
+// Clients are told apart by the key of the group: with auto, by the JWT subject, else by
+// the API key, else by the IP address. Only the API keys verified by the app, stored in
+// the context with auth.WithAPIKey, are counted apart: a key sent in a header is not
+// trusted. It goes after the real_ip middleware behind a proxy, and after auth,
+// auth_optional or the middleware of the API keys.
+func (s *Socle) RateLimitMiddleware(next http.Handler) http.Handler {
+	// the limiters are looked up for each request, the routes being registered before
+	// initRateLimiter creates them
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l, by := s.RateLimiter, s.appConfig.RateLimit.Key
+		for _, rule := range s.rateLimits {
+			if pathUnder(r.URL.Path, rule.path) {
+				l, by = rule.limiter, rule.key
+				break
+			}
+		}
+		if l != nil && !s.allow(w, r, l, by) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimit returns a middleware limiting each client to requests per window, to limit
+// some routes only. The routes limited under the same name share their counts:
+//
+//	r.With(s.RateLimit("login", 5, time.Minute)).Post("/login", h.Login)
+//
+// It panics when the store of the limiter cannot be opened, the routes being set up at
+// startup.
+func (s *Socle) RateLimit(name string, requests int, window time.Duration) func(http.Handler) http.Handler {
+	limiter, err := s.newLimiter(name, requests, window)
+	if err != nil {
+		panic(err)
+	}
+	return s.limit(limiter, s.appConfig.RateLimit.Key)
+}
+
+// limit returns a middleware counting the requests with l.
+func (s *Socle) limit(l ratelimiter.Limiter, by string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.allow(w, r, l, by) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow counts the request r with l and sets the RateLimit headers. Once the limit is
+// reached, it answers 429 with a Retry-After header and returns false.
+func (s *Socle) allow(w http.ResponseWriter, r *http.Request, l ratelimiter.Limiter, by string) bool {
+	res := l.Allow(s.rateLimitKey(r, by))
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+	if !res.Allowed {
+		s.rateLimited(entryFromContext(r.Context()))
+		h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+		s.errorResponse(w, r, http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// rateLimitKey returns the key of the client of r: its JWT subject, authenticated API key
+// or IP address.
+func (s *Socle) rateLimitKey(r *http.Request, by string) string {
+	if by == "" || by == "auto" || by == "subject" {
+		if user := auth.Username(r.Context()); user != "" {
+			return "sub:" + user
+		}
+	}
+	if by == "" || by == "auto" || by == "api_key" {
+		if key := auth.APIKey(r.Context()); key != "" {
+			// keep the keys themselves out of the limiter
+			sum := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+	}
+	return "ip:" + clientIP(r.RemoteAddr)
+}
+
+// pathUnder reports whether path is prefix or one of its sub paths.
+func pathUnder(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,149 @@
+package socle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/ratelimiter"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	s := &Socle{}
+	s.env.rateLimiter = ratelimiter.Config{
+		Enabled:              true,
+		RequestsPerTimeFrame: 3,
+		TimeFrame:            time.Minute,
+		Store:                "memory",
+	}
+	s.appConfig.RateLimit.Groups = []rateLimitGroup{
+		{Path: "/api", Requests: 2, Window: time.Minute},
+		{Path: "/api/login/", Requests: 1, Window: time.Minute},
+	}
+
+	// the routes are registered by New before initRateLimiter runs
+	r := chi.NewRouter()
+	r.Use(s.RateLimitMiddleware)
+	r.Get("/*", text("ok"))
+	if err := s.initRateLimiter(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		ip     string
+		status int
+		limit  string
+	}{
+		// the most specific group wins
+		{"/api/login", "10.0.0.1", http.StatusOK, "1"},
+		{"/api/login", "10.0.0.1", http.StatusTooManyRequests, "1"},
+		{"/api/login", "10.0.0.2", http.StatusOK, "1"},
+		{"/api/users", "10.0.0.1", http.StatusOK, "2"},
+		{"/api", "10.0.0.1", http.StatusOK, "2"},
+		{"/api/users/1", "10.0.0.1", http.StatusTooManyRequests, "2"},
+		// a path only starting like a group is not in it
+		{"/apis", "10.0.0.1", http.StatusOK, "3"},
+		// the other paths fall back to the default limiter
+		{"/", "10.0.0.1", http.StatusOK, "3"},
+		{"/about", "10.0.0.1", http.StatusOK, "3"},
+		{"/about", "10.0.0.1", http.StatusTooManyRequests, "3"},
+		{"/about", "10.0.0.2", http.StatusOK, "3"},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%d: GET %s from %s: status = %d, want %d", i, tt.path, tt.ip, rec.Code, tt.status)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != tt.limit {
+			t.Errorf("%d: GET %s: RateLimit-Limit = %q, want %q", i, tt.path, got, tt.limit)
+		}
+		if tt.status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%d: GET %s: no Retry-After", i, tt.path)
+		}
+	}
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	s := &Socle{}
+	r := chi.NewRouter()
+	r.Use(s.RateLimitMiddleware)
+	r.Get("/", text("ok"))
+	if err := s.initRateLimiter(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("status = %d, RateLimit-Limit = %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestRateLimitAPIKey(t *testing.T) {
+	for _, by := range []string{"auto", "api_key"} {
+		t.Run(by, func(t *testing.T) {
+			s := &Socle{}
+			s.env.rateLimiter = ratelimiter.Config{
+				Enabled:              true,
+				RequestsPerTimeFrame: 2,
+				TimeFrame:            time.Minute,
+				Store:                "memory",
+			}
+			s.appConfig.RateLimit.Key = by
+
+			// the API keys of the app: only "valid" is known
+			apiKeys := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Header.Get("X-API-Key") == "valid" {
+						r = r.WithContext(auth.WithAPIKey(r.Context(), "key-1"))
+					}
+					next.ServeHTTP(w, r)
+				})
+			}
+			r := chi.NewRouter()
+			r.Use(apiKeys, s.RateLimitMiddleware)
+			r.Get("/", text("ok"))
+			if err := s.initRateLimiter(); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				ip     string
+				key    string
+				status int
+			}{
+				{"10.0.0.1", "random-1", http.StatusOK},
+				{"10.0.0.1", "random-2", http.StatusOK},
+				// rotating an unknown key does not reset the count of the address
+				{"10.0.0.1", "random-3", http.StatusTooManyRequests},
+				{"10.0.0.1", "", http.StatusTooManyRequests},
+				// a verified key is counted apart from its address
+				{"10.0.0.1", "valid", http.StatusOK},
+				{"10.0.0.2", "valid", http.StatusOK},
+				{"10.0.0.3", "valid", http.StatusTooManyRequests},
+			}
+			for i, tt := range tests {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = tt.ip + ":1234"
+				if tt.key != "" {
+					req.Header.Set("X-API-Key", tt.key)
+				}
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				if rec.Code != tt.status {
+					t.Errorf("%d: GET / from %s with key %q: status = %d, want %d", i, tt.ip, tt.key, rec.Code, tt.status)
+				}
+			}
+		})
+	}
+}
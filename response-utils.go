@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"path"
 	"path/filepath"
+	"strings"
 )
 
 func (c *Socle) ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}) error {
@@ -93,14 +94,34 @@ func (c *Socle) Error500(w http.ResponseWriter, r *http.Request) {
 	c.ErrorStatus(w, http.StatusInternalServerError)
 }
 
-// ErrorUnauthorized sends an unauthorized status (client is not known)
+// ErrorUnauthorized sends an unauthorized status (client is not known), as JSON to the
+// clients of the api entries and those asking for JSON
 func (c *Socle) ErrorUnauthorized(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusUnauthorized)
+	c.errorResponse(w, r, http.StatusUnauthorized)
 }
 
-// ErrorForbidden returns a forbidden status message (client is known)
+// ErrorForbidden returns a forbidden status message (client is known), as JSON to the
+// clients of the api entries and those asking for JSON
 func (c *Socle) ErrorForbidden(w http.ResponseWriter, r *http.Request) {
-	c.ErrorStatus(w, http.StatusForbidden)
+	c.errorResponse(w, r, http.StatusForbidden)
+}
+
+// errorResponse sends status with a JSON body to the api clients, or as plain text.
+func (c *Socle) errorResponse(w http.ResponseWriter, r *http.Request, status int) {
+	if wantsJSON(r) || strings.HasPrefix(entryFromContext(r.Context()), "api/") {
+		_ = c.WriteJSON(w, status, map[string]any{
+			"error":   true,
+			"message": http.StatusText(status),
+		})
+		return
+	}
+	c.ErrorStatus(w, status)
+}
+
+// wantsJSON reports whether the client of r expects a JSON response.
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.Contains(r.Header.Get("Content-Type"), "application/json")
 }
 
 // ErrorStatus returns a response with the supplied http status
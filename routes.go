@@ -4,16 +4,12 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
-func (s *Socle) routes(middlewares []string) http.Handler {
+func (s *Socle) routes(e *Entry) http.Handler {
 	mux := chi.NewRouter()
-	s.applyMiddlewares(mux, middlewares)
-
-	if s.Debug {
-		mux.Use(middleware.Logger)
-	}
+	mux.Use(s.logContext(e.Name))
+	s.applyMiddlewares(mux, e.Server.Middlewares)
 
 	return mux
 }
@@ -27,3 +23,53 @@ func Routes() http.Handler {
 	})
 	return r
 }
+
+// authMiddlewares are the middlewares the public routes of socle are mounted without.
+var authMiddlewares = []string{"auth", "auth_optional", "basic_auth"}
+
+// mount registers routes of socle on the entry, behind its middlewares. They are mounted
+// by ListenAndServe, once the application has set up its own middlewares and routes:
+// chi builds the middleware chain of a mux with its first route.
+func (e *Entry) mount(fn func(r chi.Router)) {
+	e.mounts = append(e.mounts, fn)
+}
+
+// mountPublic registers routes of socle on the entry, behind its middlewares except the
+// authentication ones, like the refresh of the expired tokens.
+func (e *Entry) mountPublic(fn func(r chi.Router)) {
+	e.publicMounts = append(e.publicMounts, fn)
+}
+
+// mountRoutes mounts the routes of socle on the HTTP entries, and sets their handler.
+func (s *Socle) mountRoutes() {
+	for _, e := range s.Entries {
+		if e.Routes == nil || e.handler != nil {
+			continue
+		}
+		for _, fn := range e.mounts {
+			fn(e.Routes)
+		}
+		if len(e.publicMounts) == 0 {
+			e.handler = e.Routes
+			continue
+		}
+
+		var middlewares []string
+		for _, name := range e.Server.Middlewares {
+			if !InArrayStr(name, authMiddlewares) {
+				middlewares = append(middlewares, name)
+			}
+		}
+		// e.Routes sets the entry context itself, only the public routes need it here
+		outer := chi.NewRouter()
+		outer.Group(func(r chi.Router) {
+			r.Use(s.logContext(e.Name))
+			s.applyMiddlewares(r, middlewares)
+			for _, fn := range e.publicMounts {
+				fn(r)
+			}
+		})
+		outer.Mount("/", e.Routes)
+		e.handler = outer
+	}
+}
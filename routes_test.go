@@ -0,0 +1,127 @@
+package socle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/socle/pkg/logging"
+)
+
+func text(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestMountRoutesAfterApplication(t *testing.T) {
+	s := &Socle{}
+	e := &Entry{Name: "api/rest", Server: Server{Middlewares: []string{"request_id", "basic_auth"}}}
+	e.Routes = s.routes(e).(*chi.Mux)
+	s.Entries = []*Entry{e}
+	s.env.auth.basic.user, s.env.auth.basic.pass = "admin", "secret"
+
+	// registered by New, before the application sets up its router
+	e.mount(func(r chi.Router) { r.Get("/graphql", text("graphql")) })
+	e.mountPublic(func(r chi.Router) { r.Post("/refresh", text("refresh")) })
+
+	// the application adds a middleware after New, which chi refuses once a route exists
+	e.Routes.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-App", "1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	e.Routes.Get("/", text("home"))
+	e.Routes.Get("/users/{id}", text("user"))
+
+	s.mountRoutes()
+
+	tests := []struct {
+		method, path string
+		login        bool
+		status       int
+		body         string
+		app          bool
+	}{
+		{"GET", "/", true, http.StatusOK, "home", true},
+		{"GET", "/users/1", true, http.StatusOK, "user", true},
+		{"GET", "/graphql", true, http.StatusOK, "graphql", true},
+		{"GET", "/graphql", false, http.StatusUnauthorized, "", false},
+		{"GET", "/missing", true, http.StatusNotFound, "", true},
+		// the public routes skip the authentication middlewares
+		{"POST", "/refresh", false, http.StatusOK, "refresh", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.login {
+				req.SetBasicAuth("admin", "secret")
+			}
+			rec := httptest.NewRecorder()
+			e.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if got := rec.Header().Get("X-App") == "1"; got != tt.app {
+				t.Errorf("application middleware ran = %v, want %v", got, tt.app)
+			}
+		})
+	}
+}
+
+func TestMountRoutesWithoutPublicRoutes(t *testing.T) {
+	s := &Socle{}
+	e := &Entry{Name: "api/graphql"}
+	e.Routes = s.routes(e).(*chi.Mux)
+	s.Entries = []*Entry{e}
+
+	e.mount(func(r chi.Router) { r.Get("/graphql", text("graphql")) })
+	e.Routes.Use(func(next http.Handler) http.Handler { return next })
+	s.mountRoutes()
+	s.mountRoutes()
+
+	rec := httptest.NewRecorder()
+	e.handler.ServeHTTP(rec, httptest.NewRequest("GET", "/graphql", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "graphql" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMountRoutesEntryContext(t *testing.T) {
+	s := &Socle{}
+	e := &Entry{Name: "api/rest"}
+	e.Routes = s.routes(e).(*chi.Mux)
+	s.Entries = []*Entry{e}
+
+	// entries counts the entry fields logged by the requests
+	entries := func(w http.ResponseWriter, r *http.Request) {
+		n := 0
+		for _, a := range logging.Attrs(r.Context()) {
+			if a.Key == "entry" {
+				n++
+			}
+		}
+		w.Header().Set("X-Entries", strconv.Itoa(n))
+		w.Header().Set("X-Entry", entryFromContext(r.Context()))
+	}
+	e.mountPublic(func(r chi.Router) { r.Post("/refresh", entries) })
+	e.Routes.Get("/", entries)
+	s.mountRoutes()
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/", nil),
+		httptest.NewRequest("POST", "/refresh", nil),
+	} {
+		rec := httptest.NewRecorder()
+		e.handler.ServeHTTP(rec, req)
+		if n, entry := rec.Header().Get("X-Entries"), rec.Header().Get("X-Entry"); n != "1" || entry != "api/rest" {
+			t.Errorf("%s %s: %s entry fields, entry %q, want 1 of api/rest", req.Method, req.URL.Path, n, entry)
+		}
+	}
+}
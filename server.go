@@ -1,62 +1,149 @@
 package socle
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"log"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// ListenAndServe mounts the routes of socle after those of the application, starts the
+// servers of every HTTP and gRPC entry and the job workers of the worker entry run by the
+// process, then blocks until a server fails or the process receives
+// SIGINT or SIGTERM. On a signal, the servers stop accepting connections and wait up to
+// SHUTDOWN_TIMEOUT seconds for in-flight requests, running jobs, the scheduler and the
+// mailer before running the shutdown hooks and closing the shared connections.
 func (s *Socle) ListenAndServe() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	srv := &http.Server{
-		Addr:         s.Server.getURL(),
-		ErrorLog:     s.Log.ErrorLog,
-		Handler:      s.Routes,
-		IdleTimeout:  30 * time.Second,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 600 * time.Second,
+	s.mountRoutes()
+	servers := make(map[*Entry]*http.Server)
+	for _, e := range s.Entries {
+		if e.Routes == nil {
+			continue
+		}
+		srv := &http.Server{
+			Addr:         e.Server.getURL(),
+			ErrorLog:     s.Log.ErrorLog,
+			Handler:      e.handler,
+			IdleTimeout:  30 * time.Second,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 600 * time.Second,
+		}
+		if e.Server.Secure {
+			cfg, err := s.tlsConfig(e.Server)
+			if err != nil {
+				_ = s.releaseResources(context.Background())
+				return fmt.Errorf("%s: %w", e.Name, err)
+			}
+			srv.TLSConfig = cfg
+		}
+		servers[e] = srv
 	}
 
-	if s.DB.Pool != nil {
-		defer s.DB.Pool.Close()
+	if err := s.runStartHooks(ctx); err != nil {
+		_ = s.releaseResources(context.Background())
+		return err
 	}
 
-	if redisPool != nil {
-		defer redisPool.Close()
+	s.listenRPC()
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	workerDone := s.startWorker(workerCtx)
+	go s.Maintenance.Watch(workerCtx, s.env.maintenance.refresh)
+	go s.watchCertificates(workerCtx)
+
+	aux := s.auxServers()
+	serverErr := make(chan error, len(servers)+len(aux)+1)
+	for e, srv := range servers {
+		go func() {
+			serverErr <- s.serve(e, srv)
+		}()
+	}
+	for _, srv := range aux {
+		go func() {
+			s.Log.InfoLog.Printf("Listening on  %s", srv.Addr)
+			serverErr <- srv.ListenAndServe()
+		}()
+	}
+	if s.GRPC != nil {
+		go func() {
+			serverErr <- s.serveGRPC(s.Entry("api/grpc"))
+		}()
 	}
 
-	if badgerConn != nil {
-		defer badgerConn.Close()
+	var err error
+	select {
+	case err = <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case <-ctx.Done():
+		s.Log.InfoLog.Println("Shutting down, draining connections")
+	case <-s.stop:
+		s.Log.InfoLog.Println("Shutdown requested, draining connections")
 	}
+	stop()
 
-	go s.listenRPC()
-	s.Log.InfoLog.Printf("Listening on  %s with security %v", s.Server.getURL(), s.Server.Secure)
-	if s.Server.Secure {
-		s.Log.InfoLog.Println("Begin TLS  Security")
-		if s.Server.Security.Strategy == "self" {
-			srv.TLSConfig = &tls.Config{
-				MinVersion: tls.VersionTLS13,
-			}
-			caBytes, err := os.ReadFile(s.Server.Security.CAName + ".crt")
-			if err != nil {
-				log.Fatal(err)
-			}
-			ca := x509.NewCertPool()
-			if !ca.AppendCertsFromPEM(caBytes) {
-				log.Fatal("CA cert not valid")
-			}
-			srv.TLSConfig.ClientCAs = ca
+	// fail the readiness probe first, so that load balancers stop sending requests
+	s.shuttingDown.Store(true)
+	if s.grpcHealth != nil {
+		s.grpcHealth.Shutdown()
+	}
+	if s.env.health.shutdownDelay > 0 {
+		time.Sleep(s.env.health.shutdownDelay)
+	}
 
-			if s.Server.Security.MutualTLS {
-				srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.env.shutdown)
+	defer cancel()
 
-			}
+	// the workers stop reserving jobs and drain the running ones while the servers shut down
+	stopWorker()
+
+	for _, srv := range servers {
+		if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = errors.Join(err, shutdownErr)
+		}
+	}
+	for _, srv := range aux {
+		if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = errors.Join(err, shutdownErr)
 		}
+	}
+
+	if s.GRPC != nil {
+		if grpcErr := s.stopGRPC(shutdownCtx); grpcErr != nil {
+			err = errors.Join(err, grpcErr)
+		}
+	}
 
-		return srv.ListenAndServeTLS(s.Server.Security.ServerCertName+".crt", s.Server.Security.ServerCertName+".key")
+	select {
+	case <-workerDone:
+	case <-shutdownCtx.Done():
+		err = errors.Join(err, errors.New("worker: running jobs did not finish before shutdown timeout"))
+	}
+
+	if releaseErr := s.releaseResources(shutdownCtx); releaseErr != nil {
+		err = errors.Join(err, releaseErr)
+	}
+
+	if err == nil {
+		s.Log.InfoLog.Println("Server stopped")
+	}
+	return err
+}
+
+func (s *Socle) serve(e *Entry, srv *http.Server) error {
+	s.Log.InfoLog.Printf("%s listening on  %s with security %v", e.Name, e.Server.getURL(), e.Server.Secure)
+	if srv.TLSConfig != nil {
+		s.Log.InfoLog.Println("Begin TLS  Security")
+		return srv.ListenAndServeTLS("", "")
 	}
 	s.Log.InfoLog.Println("Skip TLS  Security")
 	return srv.ListenAndServe()
@@ -1,21 +1,16 @@
 package socle
 
 import (
-	"log"
-	"net"
-	"net/rpc"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
-	"github.com/go-chi/chi/v5"
 	"github.com/gomodule/redigo/redis"
 	"github.com/robfig/cron/v3"
 	"github.com/socle-framework/cache"
 	"github.com/socle-framework/mailer"
 	"github.com/socle-framework/render"
 	"github.com/socle-framework/session"
-	"github.com/socle-framework/socle/pkg/auth"
 	"github.com/socle-framework/socle/pkg/env"
 )
 
@@ -25,10 +20,11 @@ var redisCache *cache.RedisCache
 var badgerCache *cache.BadgerCache
 var redisPool *redis.Pool
 var badgerConn *badger.DB
-var maintenanceMode bool
 
 // New reads the .env file, creates our application config, populates the Socle type with settings
-// based on .env values, and creates necessary folders and files if they don't exist
+// based on .env values, and creates necessary folders and files if they don't exist.
+// entry is the part of the application to run (web, api/rest, worker), or "supervisor"
+// to run every enabled entry of socle.yaml in this process.
 func (s *Socle) New(rootPath, entry string) error {
 	s.entry = entry
 	// Load .env
@@ -50,6 +46,8 @@ func (s *Socle) New(rootPath, entry string) error {
 	s.EncryptionKey = s.env.encryptionKey
 	s.Version = version
 	s.RootPath = rootPath
+	s.startedAt = time.Now()
+	s.stop = make(chan struct{})
 
 	// create loggers
 	err = s.initLoggers()
@@ -57,8 +55,14 @@ func (s *Socle) New(rootPath, entry string) error {
 		return err
 	}
 
-	// init router
-	err = s.initRouter()
+	// install the tracer provider
+	err = s.initTracing()
+	if err != nil {
+		return err
+	}
+
+	// init entries, with their router and server
+	err = s.initEntries()
 	if err != nil {
 		return err
 	}
@@ -83,8 +87,20 @@ func (s *Socle) New(rootPath, entry string) error {
 		return err
 	}
 
-	// init server
-	err = s.initServer()
+	// init ACME certificates
+	err = s.initACME()
+	if err != nil {
+		return err
+	}
+
+	// init job queue
+	err = s.initJobs()
+	if err != nil {
+		return err
+	}
+
+	// load maintenance state
+	err = s.initMaintenance()
 	if err != nil {
 		return err
 	}
@@ -101,12 +117,32 @@ func (s *Socle) New(rootPath, entry string) error {
 		return err
 	}
 
+	// load the translations
+	err = s.initI18n()
+	if err != nil {
+		return err
+	}
+
 	// init Mailer
 	err = s.initMailer()
 	if err != nil {
 		return err
 	}
-	go s.Mail.ListenForMail()
+	s.mailStop = make(chan struct{})
+	s.mailDone = make(chan struct{})
+	go s.listenForMail()
+
+	// register the health checks
+	err = s.initHealth()
+	if err != nil {
+		return err
+	}
+
+	// register the metrics collectors
+	err = s.initMetrics()
+	if err != nil {
+		return err
+	}
 
 	//init auth
 	err = s.initAuthentificator()
@@ -114,29 +150,24 @@ func (s *Socle) New(rootPath, entry string) error {
 		return err
 	}
 
-	return nil
-}
-
-func (s *Socle) initLoggers() error {
-	s.Log.InfoLog = log.New(os.Stdout, s.entry+" INFO\t", log.Ldate|log.Ltime)
-	s.Log.ErrorLog = log.New(os.Stdout, s.entry+" ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
-	return nil
-}
+	// load the basic auth users
+	err = s.initBasicAuth()
+	if err != nil {
+		return err
+	}
 
-func (s *Socle) initRouter() error {
-	if !InArrayStr(s.entry, []string{"web", "api/rest"}) {
-		return nil
+	// discover the oauth providers
+	err = s.initOAuth()
+	if err != nil {
+		return err
 	}
-	var middlewares []string
-	switch s.entry {
-	case "api/rest":
-		middlewares = s.appConfig.Entries.Api.Middlewares
 
-	default:
-		middlewares = s.appConfig.Entries.Web.Middlewares
+	// create the rate limiters
+	err = s.initRateLimiter()
+	if err != nil {
+		return err
 	}
-	s.Server.Middlewares = middlewares
-	s.Routes = s.routes(middlewares).(*chi.Mux)
+
 	return nil
 }
 
@@ -173,7 +204,7 @@ func (s *Socle) initCache() error {
 		s.Cache = badgerCache
 		badgerConn = badgerCache.Conn
 
-		_, err := s.Scheduler.AddFunc("@daily", func() {
+		_, err := s.Schedule("badger_gc", "@daily", func() {
 			_ = badgerCache.Conn.RunValueLogGC(0.7)
 		})
 		if err != nil {
@@ -181,6 +212,7 @@ func (s *Socle) initCache() error {
 		}
 	}
 
+	s.traceCache()
 	return nil
 }
 
@@ -192,6 +224,23 @@ func (s *Socle) createClientRedisCache() *cache.RedisCache {
 	return &cacheClient
 }
 
+// redisPrefix returns REDIS_PREFIX followed by the colon the cache puts between its
+// prefix and its keys, for the keys of the other redis stores.
+func (s *Socle) redisPrefix() string {
+	prefix := s.env.redis.prefix
+	if prefix != "" && !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	return prefix
+}
+
+// devMode reports whether MODE=dev, which accepts the insecure defaults of a local
+// checkout, such as the admin/admin basic auth or a short AUTH_TOKEN_SECRET. MODE defaults
+// to production, unlike DEBUG, so that they are refused unless the app opts in.
+func (s *Socle) devMode() bool {
+	return s.env.mode == "dev"
+}
+
 func (s *Socle) createClientBadgerCache() *cache.BadgerCache {
 	cacheClient := cache.BadgerCache{
 		Conn: s.createBadgerConn(),
@@ -246,38 +295,6 @@ func (s *Socle) initMailer() error {
 	return nil
 }
 
-func (s *Socle) initServer() error {
-	if !InArrayStr(s.entry, []string{"web", "api/rest"}) {
-		return nil
-	}
-
-	s.Server = Server{
-		Name:    s.env.serverName,
-		Address: s.env.serverAddress,
-	}
-	switch s.entry {
-	case "api/rest":
-		s.Server.Port = s.env.restApiPort
-		s.Server.Secure = s.appConfig.Entries.Api.Security.Enabled
-		s.Server.Security.Strategy = s.appConfig.Entries.Api.Security.TLS.Strategy
-		s.Server.Security.MutualTLS = s.appConfig.Entries.Api.Security.TLS.Mutual
-		s.Server.Security.CAName = s.appConfig.Entries.Api.Security.TLS.CACertName
-		s.Server.Security.ServerCertName = s.appConfig.Entries.Api.Security.TLS.ServerCertName
-		s.Server.Security.ClientCertName = s.appConfig.Entries.Api.Security.TLS.ClientCertName
-
-	default:
-		s.Server.Port = s.env.webPort
-		s.Server.Secure = s.appConfig.Entries.Web.Security.Enabled
-		s.Server.Security.Strategy = s.appConfig.Entries.Web.Security.TLS.Strategy
-		s.Server.Security.MutualTLS = s.appConfig.Entries.Web.Security.TLS.Mutual
-		s.Server.Security.CAName = s.appConfig.Entries.Web.Security.TLS.CACertName
-		s.Server.Security.ServerCertName = s.appConfig.Entries.Web.Security.TLS.ServerCertName
-		s.Server.Security.ClientCertName = s.appConfig.Entries.Web.Security.TLS.ClientCertName
-	}
-	return nil
-
-}
-
 func (s *Socle) InitSession() error {
 	// if s.entry != "web" {
 	// 	return nil
@@ -305,7 +322,7 @@ func (s *Socle) InitSession() error {
 }
 
 func (s *Socle) initRenderer() error {
-	if s.entry != "web" {
+	if !s.runs("web") {
 		return nil
 	}
 
@@ -331,55 +348,3 @@ func (s *Socle) initRenderer() error {
 	}
 	return nil
 }
-
-func (s *Socle) initAuthentificator() error {
-	if s.entry != "api/rest" {
-		return nil
-	}
-
-	s.Authenticator = auth.NewJWTAuthenticator(
-		s.env.auth.token.secret,
-		s.env.auth.token.iss,
-		s.env.auth.token.iss,
-	)
-
-	return nil
-}
-
-type RPCServer struct{}
-
-func (r *RPCServer) MaintenanceMode(inMaintenanceMode bool, resp *string) error {
-	if inMaintenanceMode {
-		maintenanceMode = true
-		*resp = "Server in maintenance mode"
-	} else {
-		maintenanceMode = false
-		*resp = "Server live!"
-	}
-	return nil
-}
-
-func (s *Socle) listenRPC() {
-	// if nothing specified for rpc port, don't start
-	if os.Getenv("RPC_PORT") != "" {
-		s.Log.InfoLog.Println("Starting RPC server on port", os.Getenv("RPC_PORT"))
-		err := rpc.Register(new(RPCServer))
-		if err != nil {
-			s.Log.ErrorLog.Println(err)
-			return
-		}
-		listen, err := net.Listen("tcp", "127.0.0.1:"+os.Getenv("RPC_PORT"))
-		if err != nil {
-			s.Log.ErrorLog.Println(err)
-			return
-		}
-		for {
-			rpcConn, err := listen.Accept()
-			if err != nil {
-				continue
-			}
-			go rpc.ServeConn(rpcConn)
-		}
-
-	}
-}
@@ -0,0 +1,105 @@
+package socle
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/socle-framework/socle/pkg/certs"
+	"golang.org/x/crypto/acme"
+)
+
+// tlsConfig builds the TLS configuration of srv for its strategy:
+//   - le: certificates issued and renewed by ACME, with the TLS-ALPN-01 challenge;
+//   - root: certificate files, client certificates verified against the system roots;
+//   - self: certificate files, client certificates verified against our own CA.
+//
+// Certificate files are reloaded when they change or when the process receives SIGHUP.
+func (s *Socle) tlsConfig(srv Server) (*tls.Config, error) {
+	if srv.Security.Strategy == "le" {
+		// the gRPC server is created with the entries, before initACME: the certificate
+		// manager is looked up at the handshake
+		return &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			NextProtos: []string{"h2", "http/1.1", acme.ALPNProto},
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				if s.acme == nil {
+					return nil, errors.New("acme: no certificate manager")
+				}
+				return s.acme.GetCertificate(hello)
+			},
+		}, nil
+	}
+
+	base := &tls.Config{}
+	var caFile string
+
+	switch srv.Security.Strategy {
+	case "root":
+		base.MinVersion = tls.VersionTLS12
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		base.ClientCAs = roots
+
+		if srv.Security.MutualTLS {
+			base.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+	case "self":
+		base.MinVersion = tls.VersionTLS13
+		caFile = srv.Security.CAName + ".crt"
+
+		if srv.Security.MutualTLS {
+			base.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r, err := certs.NewReloader(srv.Security.ServerCertName+".crt", srv.Security.ServerCertName+".key", caFile)
+	if err != nil {
+		return nil, err
+	}
+	r.InfoLog = s.Log.InfoLog
+	r.ErrorLog = s.Log.ErrorLog
+	s.certReloaders = append(s.certReloaders, r)
+
+	return r.TLSConfig(base), nil
+}
+
+// watchCertificates reloads the certificate files every CERT_RELOAD_INTERVAL seconds when
+// they changed, and on SIGHUP, until ctx is done.
+func (s *Socle) watchCertificates(ctx context.Context) {
+	if len(s.certReloaders) == 0 {
+		return
+	}
+
+	if s.env.certReloadInterval > 0 {
+		for _, r := range s.certReloaders {
+			go r.Watch(ctx, s.env.certReloadInterval)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			for _, r := range s.certReloaders {
+				if err := r.Reload(); err != nil {
+					s.Log.ErrorLog.Println("unable to reload certificates:", err)
+					continue
+				}
+				s.Log.InfoLog.Println("Certificates reloaded from", r.CertFile)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package socle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// cachedCertificate stores a certificate of domain in cache, the way autocert does once
+// it is issued.
+func cachedCertificate(t *testing.T, cache autocert.Cache, domain string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	if err := cache.Put(context.Background(), domain, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects to a server configured with cfg and returns the negotiated protocol.
+func handshake(cfg *tls.Config, serverName string) (string, error) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_ = tls.Server(server, cfg).Handshake()
+		server.Close()
+	}()
+	conn := tls.Client(client, &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{"h2"},
+		InsecureSkipVerify: true,
+	})
+	if err := conn.Handshake(); err != nil {
+		return "", err
+	}
+	return conn.ConnectionState().NegotiatedProtocol, nil
+}
+
+func TestTLSConfigLEBeforeACME(t *testing.T) {
+	s := &Socle{}
+	srv := Server{Secure: true, Security: ServerSecurity{Strategy: "le"}}
+
+	// the gRPC entry builds its configuration before initACME runs
+	cfg, err := s.tlsConfig(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handshake(cfg, "example.test"); err == nil {
+		t.Fatal("handshake succeeded without a certificate manager")
+	}
+
+	cache := autocert.DirCache(t.TempDir())
+	cachedCertificate(t, cache, "example.test")
+	s.acme = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist("example.test"),
+		Cache:      cache,
+	}
+
+	proto, err := handshake(cfg, "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto != "h2" {
+		t.Errorf("negotiated protocol = %q, want h2", proto)
+	}
+}
@@ -0,0 +1,329 @@
+package socle
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/socle-framework/cache"
+	"github.com/socle-framework/mailer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tracerName = "github.com/socle-framework/socle"
+
+// initTracing installs the tracer provider described by the tracing section of socle.yaml,
+// exporting the spans with OTLP over gRPC, to stdout or to a file.
+func (s *Socle) initTracing() error {
+	cfg := s.appConfig.Tracing
+	if !cfg.Enabled {
+		s.tracer = noop.NewTracerProvider().Tracer(tracerName)
+		return nil
+	}
+
+	exporter, err := s.spanExporter(cfg)
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = s.appConfig.Name
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(name),
+		semconv.ServiceVersion(s.appConfig.Version),
+		attribute.String("socle.entry", s.entry),
+	))
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+
+	ratio := 1.0
+	if cfg.SampleRatio != nil {
+		ratio = *cfg.SampleRatio
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	s.tracerProvider = tp
+	s.tracer = tp.Tracer(tracerName)
+	return nil
+}
+
+func (s *Socle) spanExporter(cfg tracingConfig) (sdktrace.SpanExporter, error) {
+	if s.traceExporter != nil {
+		return s.traceExporter, nil
+	}
+
+	switch cfg.Exporter {
+	case "", "otlp":
+		opts := []otlptracegrpc.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+
+	case "file":
+		path := cfg.File
+		if path == "" {
+			path = "tmp/traces.json"
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.RootPath, path)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	}
+	return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+}
+
+// shutdownTracing flushes the spans still buffered and stops the exporter.
+func (s *Socle) shutdownTracing(ctx context.Context) error {
+	if s.tracerProvider == nil {
+		return nil
+	}
+	return s.tracerProvider.Shutdown(ctx)
+}
+
+// TracingMiddleware starts a server span for every request, continuing the trace of the
+// W3C traceparent header when present, and names it after the chi route pattern.
+func (s *Socle) TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := s.tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(r.RemoteAddr),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				attribute.String("socle.entry", entryFromContext(ctx)),
+			))
+		defer span.End()
+
+		// let the client correlate the response with the trace
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// InjectTraceContext adds the W3C trace context of ctx to the headers of an outgoing request.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// metadataCarrier adapts gRPC metadata to the propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *Socle) grpcTracingUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, span := s.startCallSpan(ctx, info.FullMethod)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	endCallSpan(span, err)
+	return resp, err
+}
+
+func (s *Socle) grpcTracingStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := s.startCallSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	endCallSpan(span, err)
+	return err
+}
+
+func (s *Socle) startCallSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md.Copy()))
+	return s.tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			attribute.String("rpc.method", method),
+			attribute.String("socle.entry", entryFromContext(ctx)),
+		))
+}
+
+func endCallSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, code.String())
+	}
+}
+
+// traceCache wraps s.Cache to record a span per operation when tracing is enabled. Called
+// through s.Cache, the operations start traces of their own: CacheContext ties them to
+// the trace of a request instead.
+func (s *Socle) traceCache() {
+	if s.tracerProvider == nil || s.Cache == nil {
+		return
+	}
+	s.Cache = &tracedCache{next: s.Cache, ctx: context.Background(), tracer: s.tracer, system: s.env.cache}
+}
+
+// CacheContext returns s.Cache recording a span per operation, as children of the span of ctx.
+func (s *Socle) CacheContext(ctx context.Context) cache.Cache {
+	if s.Cache == nil {
+		return nil
+	}
+	next := s.Cache
+	if c, ok := next.(*tracedCache); ok {
+		next = c.next
+	}
+	return &tracedCache{next: next, ctx: ctx, tracer: s.tracer, system: s.env.cache}
+}
+
+type tracedCache struct {
+	next   cache.Cache
+	ctx    context.Context
+	tracer trace.Tracer
+	system string
+}
+
+func (c *tracedCache) span(op, key string) trace.Span {
+	_, span := c.tracer.Start(c.ctx, "cache."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", c.system),
+			attribute.String("db.operation.name", op),
+			attribute.String("cache.key", key),
+		))
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (c *tracedCache) Has(key string) (bool, error) {
+	span := c.span("has", key)
+	ok, err := c.next.Has(key)
+	endSpan(span, err)
+	return ok, err
+}
+
+func (c *tracedCache) Get(key string) (interface{}, error) {
+	span := c.span("get", key)
+	value, err := c.next.Get(key)
+	endSpan(span, err)
+	return value, err
+}
+
+func (c *tracedCache) Set(key string, value interface{}, expires ...int) error {
+	span := c.span("set", key)
+	err := c.next.Set(key, value, expires...)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracedCache) Forget(key string) error {
+	span := c.span("forget", key)
+	err := c.next.Forget(key)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracedCache) EmptyByMatch(pattern string) error {
+	span := c.span("empty_by_match", pattern)
+	err := c.next.EmptyByMatch(pattern)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracedCache) Empty() error {
+	span := c.span("empty", "")
+	err := c.next.Empty()
+	endSpan(span, err)
+	return err
+}
+
+// SendMail sends msg right away, in a span child of the span of ctx. Mails pushed on
+// Mail.Jobs are sent in the background, each in a trace of its own.
+func (s *Socle) SendMail(ctx context.Context, msg mailer.Message) error {
+	return s.sendMail(ctx, msg)
+}
+
+func (s *Socle) sendMail(ctx context.Context, msg mailer.Message) error {
+	if s.tracer == nil {
+		return s.Mail.Send(msg)
+	}
+	_, span := s.tracer.Start(ctx, "mail.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("mail.template", msg.Template),
+			attribute.String("mail.subject", msg.Subject),
+		))
+	err := s.Mail.Send(msg)
+	endSpan(span, err)
+	return err
+}
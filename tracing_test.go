@@ -0,0 +1,261 @@
+package socle
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/socle-framework/mailer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTracedSocle returns a Socle whose spans are collected by the returned exporter.
+func newTracedSocle(t *testing.T) (*Socle, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	s := &Socle{traceExporter: exporter}
+	s.appConfig.Name = "app"
+	s.appConfig.Tracing.Enabled = true
+	if err := s.initTracing(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.shutdownTracing(context.Background()) })
+	return s, exporter
+}
+
+// spans flushes the spans ended so far.
+func spans(t *testing.T, s *Socle, exporter *tracetest.InMemoryExporter) tracetest.SpanStubs {
+	t.Helper()
+	if err := s.tracerProvider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return exporter.GetSpans()
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	s, exporter := newTracedSocle(t)
+	r := chi.NewRouter()
+	r.Use(s.TracingMiddleware)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		path        string
+		traceparent string
+		name        string
+		status      int64
+		code        codes.Code
+	}{
+		{path: "/users/42", name: "GET /users/{id}", status: 200, code: codes.Unset},
+		{path: "/users/42", traceparent: "00-" + traceID + "-00f067aa0ba902b7-01", name: "GET /users/{id}", status: 200},
+		{path: "/fail", name: "GET /fail", status: 502, code: codes.Error},
+		{path: "/missing", name: "GET", status: 404},
+	}
+	for _, tt := range tests {
+		exporter.Reset()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.traceparent != "" {
+			req.Header.Set("traceparent", tt.traceparent)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		got := spans(t, s, exporter)
+		if len(got) != 1 {
+			t.Fatalf("%s: %d spans, want 1", tt.path, len(got))
+		}
+		span := got[0]
+		if span.Name != tt.name {
+			t.Errorf("%s: span name = %q, want %q", tt.path, span.Name, tt.name)
+		}
+		if status := attr(span, "http.response.status_code").AsInt64(); status != tt.status {
+			t.Errorf("%s: status attribute = %d, want %d", tt.path, status, tt.status)
+		}
+		if span.Status.Code != tt.code {
+			t.Errorf("%s: span status = %v, want %v", tt.path, span.Status.Code, tt.code)
+		}
+		if tt.traceparent != "" && span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("%s: trace %s, want the trace of the traceparent header", tt.path, span.SpanContext.TraceID())
+		}
+		if !strings.Contains(rec.Header().Get("traceparent"), span.SpanContext.TraceID().String()) {
+			t.Errorf("%s: response traceparent = %q, want the trace of the span", tt.path, rec.Header().Get("traceparent"))
+		}
+	}
+}
+
+func TestGRPCTracingUnary(t *testing.T) {
+	s, exporter := newTracedSocle(t)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}
+
+	_, err := s.grpcTracingUnary(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(grpccodes.NotFound, "no such user")
+	})
+	if err == nil {
+		t.Fatal("the error of the handler was not returned")
+	}
+
+	got := spans(t, s, exporter)
+	if len(got) != 1 {
+		t.Fatalf("%d spans, want 1", len(got))
+	}
+	span := got[0]
+	if span.Name != info.FullMethod || span.SpanContext.TraceID().String() != traceID {
+		t.Errorf("span %q in trace %s, want %q in the trace of the metadata", span.Name, span.SpanContext.TraceID(), info.FullMethod)
+	}
+	if code := attr(span, "rpc.grpc.status_code").AsInt64(); code != int64(grpccodes.NotFound) {
+		t.Errorf("status code attribute = %d, want %d", code, grpccodes.NotFound)
+	}
+	if span.Status.Code != codes.Error || len(span.Events) != 1 {
+		t.Errorf("span status = %v with %d events, want the error recorded", span.Status.Code, len(span.Events))
+	}
+}
+
+func TestTracingFileExporter(t *testing.T) {
+	dir := t.TempDir()
+	s := &Socle{RootPath: dir}
+	s.appConfig.Tracing.Enabled = true
+	s.appConfig.Tracing.Exporter = "file"
+	s.appConfig.Tracing.File = "traces.json"
+	if err := s.initTracing(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := s.tracer.Start(context.Background(), "job.send")
+	span.End()
+	if err := s.shutdownTracing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "traces.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"job.send"`) {
+		t.Errorf("traces.json = %s, want the span", data)
+	}
+}
+
+func TestTracingUnknownExporter(t *testing.T) {
+	s := &Socle{}
+	s.appConfig.Tracing.Enabled = true
+	s.appConfig.Tracing.Exporter = "memory"
+	if err := s.initTracing(); err == nil || !strings.Contains(err.Error(), "unknown exporter") {
+		t.Errorf("initTracing = %v, want an unknown exporter error", err)
+	}
+}
+
+// mapCache is a cache.Cache kept in memory.
+type mapCache map[string]interface{}
+
+func (c mapCache) Has(key string) (bool, error) {
+	_, ok := c[key]
+	return ok, nil
+}
+
+func (c mapCache) Get(key string) (interface{}, error) { return c[key], nil }
+
+func (c mapCache) Set(key string, value interface{}, expires ...int) error {
+	c[key] = value
+	return nil
+}
+
+func (c mapCache) Forget(key string) error {
+	delete(c, key)
+	return nil
+}
+
+func (c mapCache) EmptyByMatch(pattern string) error { return nil }
+
+func (c mapCache) Empty() error {
+	clear(c)
+	return nil
+}
+
+func TestTraceCache(t *testing.T) {
+	s, exporter := newTracedSocle(t)
+	s.env.cache = "redis"
+	s.Cache = mapCache{}
+	s.traceCache()
+
+	if err := s.Cache.Set("user:1", "ada"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := s.tracer.Start(context.Background(), "GET /users/{id}")
+	if v, err := s.CacheContext(ctx).Get("user:1"); err != nil || v != "ada" {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+	parent.End()
+
+	got := spans(t, s, exporter)
+	if len(got) != 3 {
+		t.Fatalf("%d spans, want set, get and the request", len(got))
+	}
+	set, get := got[0], got[1]
+	if set.Name != "cache.set" || set.Parent.IsValid() {
+		t.Errorf("span %q with parent %v, want cache.set in a trace of its own", set.Name, set.Parent.SpanID())
+	}
+	// the cache wrapped by traceCache is not traced twice through CacheContext
+	if get.Name != "cache.get" || get.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span %q with parent %v, want cache.get child of the request", get.Name, get.Parent.SpanID())
+	}
+	if system := attr(set, "db.system").AsString(); system != "redis" {
+		t.Errorf("db.system = %q", system)
+	}
+}
+
+func TestTraceCacheDisabled(t *testing.T) {
+	s := &Socle{}
+	if err := s.initTracing(); err != nil {
+		t.Fatal(err)
+	}
+	s.Cache = mapCache{}
+	s.traceCache()
+	if _, ok := s.Cache.(mapCache); !ok {
+		t.Errorf("Cache = %T, want it left alone without tracing", s.Cache)
+	}
+}
+
+func TestSendQueuedMailTracing(t *testing.T) {
+	s, exporter := newTracedSocle(t)
+	s.Log.ErrorLog = log.New(io.Discard, "", 0)
+	s.Mail.Results = make(chan mailer.Result, 1)
+
+	s.sendQueuedMail(mailer.Message{To: "ada@example.com", Subject: "Welcome", Template: "welcome"})
+	<-s.Mail.Results
+
+	got := spans(t, s, exporter)
+	if len(got) != 1 || got[0].Name != "mail.send" || got[0].Parent.IsValid() {
+		t.Fatalf("spans = %v, want one mail.send in a trace of its own", got)
+	}
+	if subject := attr(got[0], "mail.subject").AsString(); subject != "Welcome" {
+		t.Errorf("mail.subject = %q", subject)
+	}
+}
@@ -3,41 +3,99 @@ package socle
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
+	"github.com/graphql-go/handler"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
 	"github.com/socle-framework/cache"
 	"github.com/socle-framework/filesystems"
 	"github.com/socle-framework/mailer"
 	"github.com/socle-framework/render"
 	"github.com/socle-framework/socle/pkg/auth"
+	"github.com/socle-framework/socle/pkg/certs"
+	"github.com/socle-framework/socle/pkg/i18n"
+	"github.com/socle-framework/socle/pkg/jobs"
+	"github.com/socle-framework/socle/pkg/maintenance"
+	"github.com/socle-framework/socle/pkg/oauth"
 	"github.com/socle-framework/socle/pkg/ratelimiter"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 )
 
 // Socle is the overall type for the Socle package. Members that are exported in this type
 // are available to any application that uses it.
 type Socle struct {
-	appConfig     appConfig
-	env           envConfig
-	entry         string
-	AppName       string
-	Version       string
-	Debug         bool
-	RootPath      string
-	Log           Logger
-	Routes        *chi.Mux
-	Render        render.Render
-	Session       *scs.SessionManager
-	EncryptionKey string
-	Cache         cache.Cache
-	DB            Database
-	Authenticator auth.Authenticator
-	Server        Server
-	Scheduler     *cron.Cron
-	Mail          mailer.Mail
-	FileSystem    filesystems.FS
-	RateLimiter   *ratelimiter.Limiter
+	appConfig      appConfig
+	env            envConfig
+	entry          string
+	AppName        string
+	Version        string
+	Debug          bool
+	RootPath       string
+	Log            Logger
+	Logger         *slog.Logger
+	Metrics        *prometheus.Registry
+	Routes         *chi.Mux
+	Render         render.Render
+	Session        *scs.SessionManager
+	EncryptionKey  string
+	Cache          cache.Cache
+	DB             Database
+	Authenticator  auth.Authenticator
+	OAuth          oauth.Providers
+	OAuthUsers     oauth.UserMapper
+	Server         Server
+	Entries        []*Entry
+	Scheduler      *cron.Cron
+	Mail           mailer.Mail
+	Jobs           *jobs.Queue
+	Maintenance    *maintenance.Manager
+	FileSystem     filesystems.FS
+	RateLimiter    ratelimiter.Limiter
+	I18n           *i18n.Bundle
+	GRPC           *grpc.Server
+	hooks          lifecycleHooks
+	rpcListener    net.Listener
+	scheduled      sync.Map
+	debug          atomic.Bool
+	startedAt      time.Time
+	stop           chan struct{}
+	stopOnce       sync.Once
+	shuttingDown   atomic.Bool
+	mailStop       chan struct{}
+	mailStopOnce   sync.Once
+	mailDone       chan struct{}
+	health         healthChecks
+	authKeys       *auth.KeySet
+	basicUsers     auth.BasicUsers
+	basicInsecure  bool
+	rateLimits     []rateLimitRule
+	graphQL        *handler.Handler
+	grpcHealth     *health.Server
+	acme           *autocert.Manager
+	certReloaders  []*certs.Reloader
+	logLevel       *slog.LevelVar
+	baseLevel      slog.LevelVar // LOG_LEVEL, restored when debug is turned off
+	metrics        *metrics
+	tracer         trace.Tracer
+	tracerProvider *sdktrace.TracerProvider
+	traceExporter  sdktrace.SpanExporter // replaces the configured exporter, for the tests
+}
+
+type lifecycleHooks struct {
+	sync.Mutex
+	start    []Hook
+	shutdown []Hook
 }
 
 type Database struct {
@@ -0,0 +1,500 @@
+package socle
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// RuleFunc checks a field against its rule, eg the length of a string against the 3 of
+// min=3. It is not called for the nil pointers, but for the required rule.
+type RuleFunc func(f FieldValue) bool
+
+// FieldValue is the field checked by a rule.
+type FieldValue struct {
+	// Path is the key of the errors of the field in Validation.Errors, like
+	// address.city or items[0].name.
+	Path string
+	// Value is the field, its pointers dereferenced. It is invalid for a nil pointer.
+	Value reflect.Value
+	// Param is the parameter of the rule, 3 for min=3.
+	Param string
+
+	sibling func(name string) (reflect.Value, bool)
+}
+
+// String returns the value of the field as a string.
+func (f FieldValue) String() string {
+	if !f.Value.IsValid() {
+		return ""
+	}
+	if f.Value.Kind() == reflect.String {
+		return f.Value.String()
+	}
+	// fmt prints the values of the unexported embedded structs, unlike Interface
+	return fmt.Sprint(f.Value)
+}
+
+// Sibling returns the field name of the struct holding the field, by its Go or json name,
+// or the form value name, for the cross-field rules.
+func (f FieldValue) Sibling(name string) (reflect.Value, bool) {
+	if f.sibling == nil {
+		return reflect.Value{}, false
+	}
+	return f.sibling(name)
+}
+
+type validationRule struct {
+	fn      RuleFunc
+	message string
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]validationRule{
+		"required":  {fn: ruleRequired},
+		"email":     {fn: func(f FieldValue) bool { return govalidator.IsEmail(f.String()) }},
+		"url":       {fn: func(f FieldValue) bool { return govalidator.IsURL(f.String()) }},
+		"alpha":     {fn: func(f FieldValue) bool { return govalidator.IsAlpha(f.String()) }},
+		"alphanum":  {fn: func(f FieldValue) bool { return govalidator.IsAlphanumeric(f.String()) }},
+		"nospaces":  {fn: func(f FieldValue) bool { return !govalidator.HasWhitespace(f.String()) }},
+		"int":       {fn: ruleInt},
+		"float":     {fn: ruleNumeric},
+		"numeric":   {fn: ruleNumeric},
+		"date":      {fn: ruleDate},
+		"min":       {fn: func(f FieldValue) bool { return compareSize(f, func(size, n float64) bool { return size >= n }) }},
+		"max":       {fn: func(f FieldValue) bool { return compareSize(f, func(size, n float64) bool { return size <= n }) }},
+		"len":       {fn: func(f FieldValue) bool { return compareSize(f, func(size, n float64) bool { return size == n }) }},
+		"oneof":     {fn: func(f FieldValue) bool { return slices.Contains(strings.Fields(f.Param), f.String()) }},
+		"eqfield":   {fn: func(f FieldValue) bool { return compareField(f, func(c int) bool { return c == 0 }) }},
+		"nefield":   {fn: func(f FieldValue) bool { return !compareField(f, func(c int) bool { return c == 0 }) }},
+		"gtfield":   {fn: func(f FieldValue) bool { return compareField(f, func(c int) bool { return c > 0 }) }},
+		"ltfield":   {fn: func(f FieldValue) bool { return compareField(f, func(c int) bool { return c < 0 }) }},
+		"omitempty": {},
+		"dive":      {},
+	}
+
+	// ruleMessages are the messages of the rules, {param} being replaced by the parameter
+	// of the rule and {field} by the path of the field. The size rules have a message for
+	// the numbers, another for the lengths of the strings (_len) and one for the sizes of
+	// the slices and maps (_items). The validation.<key> messages of the catalogs of the
+	// locales replace them, see Validation.Localize.
+	ruleMessages = map[string]string{
+		"required":  "This field cannot be blank",
+		"email":     "Invalid email address",
+		"url":       "Invalid URL",
+		"alpha":     "This field must only contain letters",
+		"alphanum":  "This field must only contain letters and digits",
+		"nospaces":  "Spaces are not permitted",
+		"int":       "This field must be an integer",
+		"float":     "This field must be a floating point number",
+		"numeric":   "This field must be a number",
+		"date":      "This field must be a date in the form of YYYY-MM-DD",
+		"min":       "This field must be at least {param}",
+		"min_len":   "This field must be at least {param} characters long",
+		"min_items": "This field must contain at least {param} items",
+		"max":       "This field must be at most {param}",
+		"max_len":   "This field must be at most {param} characters long",
+		"max_items": "This field must contain at most {param} items",
+		"len":       "This field must be {param}",
+		"len_len":   "This field must be exactly {param} characters long",
+		"len_items": "This field must contain exactly {param} items",
+		"oneof":     "This field must be one of {param}",
+		"eqfield":   "This field must match {param}",
+		"nefield":   "This field must differ from {param}",
+		"gtfield":   "This field must be greater than {param}",
+		"ltfield":   "This field must be less than {param}",
+		"invalid":   "This field is invalid",
+	}
+)
+
+// RegisterRule registers the rule name of the validate tags, failing with message, or its
+// validation.<name> translation. The built-in rules can be replaced:
+//
+//	socle.RegisterRule("slug", "Only lowercase letters, digits and dashes", func(f socle.FieldValue) bool {
+//		return slugPattern.MatchString(f.String())
+//	})
+func RegisterRule(name, message string, fn RuleFunc) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = validationRule{fn: fn, message: message}
+}
+
+func lookupRule(name string) validationRule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	rule, ok := rules[name]
+	if !ok {
+		panic("validator: unknown rule " + name)
+	}
+	return rule
+}
+
+// ruleSpec is a rule of a validate tag, with its parameter.
+type ruleSpec struct {
+	name  string
+	param string
+}
+
+// parseRules parses a validate tag like "required,min=3,oneof=a b". The rules following
+// dive apply to the elements of a slice, array or map.
+func parseRules(tag string) (field, elems []ruleSpec) {
+	if tag == "" {
+		return nil, nil
+	}
+	for part := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		lookupRule(name)
+		if name == "dive" && elems == nil {
+			elems = []ruleSpec{}
+			continue
+		}
+		if elems != nil {
+			elems = append(elems, ruleSpec{name, param})
+		} else {
+			field = append(field, ruleSpec{name, param})
+		}
+	}
+	return field, elems
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Struct validates the fields of the struct s, or of the struct it points to, against
+// their validate tags, like a decoded JSON body:
+//
+//	type signup struct {
+//		Email    string    `json:"email" validate:"required,email"`
+//		Password string    `json:"password" validate:"required,min=8"`
+//		Confirm  string    `json:"confirm" validate:"eqfield=Password"`
+//		Role     string    `json:"role" validate:"omitempty,oneof=admin user"`
+//		Address  address   `json:"address"`
+//		Tags     []string  `json:"tags" validate:"max=5,dive,alphanum"`
+//	}
+//
+//	v := app.Validator(nil)
+//	v.Struct(&body)
+//
+// The nested structs and the elements of the slices of structs are validated too. The
+// errors are keyed by the json names of the fields (address.city, items[0].name), else by
+// their form or Go names.
+func (v *Validation) Struct(s any) {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: Struct expects a struct, got %s", rv.Kind()))
+	}
+	v.validateStruct(rv, "")
+}
+
+// Rules validates the values of Data, like a submitted form, against the rules of their
+// fields, written like the validate tags:
+//
+//	v := app.Validator(r.PostForm)
+//	v.Rules(map[string]string{
+//		"email":   "required,email",
+//		"age":     "omitempty,int,min=18",
+//		"confirm": "eqfield=password",
+//	})
+//
+// The size rules compare the numbers of the fields with an int, float or numeric rule,
+// the length of the others. The rules following dive apply to each value of the field.
+func (v *Validation) Rules(fieldRules map[string]string) {
+	// a field missing from the form is empty, like in Data.Get
+	sibling := func(name string) (reflect.Value, bool) {
+		return reflect.ValueOf(v.Data.Get(name)), true
+	}
+
+	fields := make([]string, 0, len(fieldRules))
+	for field := range fieldRules {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	for _, field := range fields {
+		specs, elems := parseRules(fieldRules[field])
+		numeric := slices.ContainsFunc(append(specs, elems...), func(r ruleSpec) bool {
+			return r.name == "int" || r.name == "float" || r.name == "numeric"
+		})
+		formValue := func(s string) reflect.Value {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); numeric && err == nil {
+				return reflect.ValueOf(n)
+			}
+			return reflect.ValueOf(s)
+		}
+
+		if elems == nil {
+			v.validateValue(field, formValue(v.Data.Get(field)), specs, nil, sibling)
+			continue
+		}
+		values := v.Data[field]
+		if !v.applyRules(field, reflect.ValueOf(values), specs, sibling) {
+			continue
+		}
+		for i, value := range values {
+			v.validateValue(fmt.Sprintf("%s[%d]", field, i), formValue(value), elems, nil, sibling)
+		}
+	}
+}
+
+func (v *Validation) validateStruct(rv reflect.Value, prefix string) {
+	t := rv.Type()
+	sibling := func(name string) (reflect.Value, bool) {
+		for i := 0; i < t.NumField(); i++ {
+			if sf := t.Field(i); sf.IsExported() && (sf.Name == name || fieldName(sf) == name) {
+				return indirect(rv.Field(i)), true
+			}
+		}
+		return reflect.Value{}, false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if sf.Anonymous && (tag == "" || !sf.IsExported()) {
+			// the fields of the embedded structs are the fields of the struct, like in json
+			if fv = indirect(fv); fv.Kind() == reflect.Struct {
+				v.validateStruct(fv, prefix)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		specs, elems := parseRules(tag)
+		v.validateValue(joinPath(prefix, fieldName(sf)), fv, specs, elems, sibling)
+	}
+}
+
+// validateValue checks the value of path against its rules, then validates the structs it
+// holds and the elements of its slices, arrays or maps against elems.
+func (v *Validation) validateValue(path string, fv reflect.Value, specs, elems []ruleSpec, sibling func(string) (reflect.Value, bool)) {
+	fv = indirect(fv)
+	if !v.applyRules(path, fv, specs, sibling) || !fv.IsValid() {
+		return
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			v.validateStruct(fv, path)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			v.validateValue(fmt.Sprintf("%s[%d]", path, i), fv.Index(i), elems, nil, sibling)
+		}
+	case reflect.Map:
+		if elems == nil {
+			return
+		}
+		iter := fv.MapRange()
+		for iter.Next() {
+			v.validateValue(fmt.Sprintf("%s[%v]", path, iter.Key()), iter.Value(), elems, nil, sibling)
+		}
+	}
+}
+
+// applyRules adds the error of the first rule fv fails, and reports whether the other
+// checks of fv should go on: false after an error, or for an empty omitempty field.
+func (v *Validation) applyRules(path string, fv reflect.Value, specs []ruleSpec, sibling func(string) (reflect.Value, bool)) bool {
+	for _, spec := range specs {
+		if spec.name == "omitempty" {
+			if isEmpty(fv) {
+				return false
+			}
+			continue
+		}
+		if !fv.IsValid() && spec.name != "required" {
+			continue
+		}
+
+		f := FieldValue{Path: path, Value: fv, Param: spec.param, sibling: sibling}
+		rule := lookupRule(spec.name)
+		if rule.fn != nil && !rule.fn(f) {
+			v.AddError(path, v.ruleMessage(spec.name, rule, f))
+			return false
+		}
+	}
+	return true
+}
+
+// ruleMessage returns the message of the rule name failed by f: its validation.<name>
+// translation, else the message of the rule.
+func (v *Validation) ruleMessage(name string, rule validationRule, f FieldValue) string {
+	key := name
+	if name == "min" || name == "max" || name == "len" {
+		switch f.Value.Kind() {
+		case reflect.String:
+			key += "_len"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			key += "_items"
+		}
+	}
+
+	message := rule.message
+	if message == "" {
+		message = ruleMessages[key]
+	}
+	if message == "" {
+		message = ruleMessages["invalid"]
+	}
+	return v.message(key, message, f.Param, f.Path)
+}
+
+func ruleRequired(f FieldValue) bool {
+	return !isEmpty(f.Value)
+}
+
+func ruleInt(f FieldValue) bool {
+	switch f.Value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Float32, reflect.Float64:
+		return f.Value.Float() == math.Trunc(f.Value.Float())
+	}
+	_, err := strconv.Atoi(strings.TrimSpace(f.String()))
+	return err == nil
+}
+
+func ruleNumeric(f FieldValue) bool {
+	if _, ok := number(f.Value); ok {
+		return true
+	}
+	_, err := strconv.ParseFloat(strings.TrimSpace(f.String()), 64)
+	return err == nil
+}
+
+func ruleDate(f FieldValue) bool {
+	if f.Value.Type() == timeType {
+		return true
+	}
+	_, err := time.Parse("2006-01-02", f.String())
+	return err == nil
+}
+
+// compareSize compares the size of f, its value for a number or its length, with the
+// number of its parameter.
+func compareSize(f FieldValue, cmp func(size, n float64) bool) bool {
+	n, err := strconv.ParseFloat(f.Param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s: invalid size %q", f.Path, f.Param))
+	}
+
+	switch f.Value.Kind() {
+	case reflect.String:
+		return cmp(float64(utf8.RuneCountInString(f.Value.String())), n)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return cmp(float64(f.Value.Len()), n)
+	}
+	size, ok := number(f.Value)
+	return ok && cmp(size, n)
+}
+
+// compareField compares f with its sibling field named by its parameter.
+func compareField(f FieldValue, cmp func(c int) bool) bool {
+	other, ok := f.Sibling(f.Param)
+	if !ok {
+		panic(fmt.Sprintf("validator: %s: unknown field %q", f.Path, f.Param))
+	}
+
+	a, aok := number(f.Value)
+	b, bok := number(other)
+	switch {
+	case aok && bok:
+		return cmp(cmpFloat(a, b))
+	case f.Value.CanInterface() && other.CanInterface() && f.Value.Type() == timeType && other.Type() == timeType:
+		return cmp(f.Value.Interface().(time.Time).Compare(other.Interface().(time.Time)))
+	}
+	return cmp(strings.Compare(f.String(), FieldValue{Value: other}.String()))
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// number returns the value of v when it is a number.
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// isEmpty reports whether v is missing: a nil pointer, a blank string, an empty slice or
+// map, or a zero value.
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// indirect dereferences the pointers and interfaces of v, returning an invalid value for
+// a nil one.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// fieldName returns the name of a field in the error paths: its json name, else its form
+// name, else its Go name.
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,259 @@
+package socle
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/socle-framework/socle/pkg/i18n"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"omitempty,len=5,numeric"`
+}
+
+type testItem struct {
+	Name string `json:"name" validate:"required,max=10"`
+	Qty  int    `json:"qty" validate:"min=1"`
+}
+
+type TestBase struct {
+	ID int `json:"id" validate:"min=1"`
+}
+
+type testSignup struct {
+	TestBase
+	Email    string       `json:"email" validate:"required,email"`
+	Password string       `json:"password" validate:"required,min=8"`
+	Confirm  string       `json:"confirm" validate:"eqfield=Password"`
+	Role     string       `json:"role" validate:"omitempty,oneof=admin user"`
+	Age      *int         `json:"age" validate:"omitempty,min=18"`
+	Address  testAddress  `json:"address"`
+	Billing  *testAddress `json:"billing"`
+	Items    []testItem   `json:"items" validate:"max=2"`
+	Tags     []string     `json:"tags" validate:"max=3,dive,alphanum"`
+	Start    time.Time    `json:"start"`
+	End      time.Time    `json:"end" validate:"omitempty,gtfield=Start"`
+	Nickname string       `form:"nickname" validate:"omitempty,nospaces"`
+	Internal string       `validate:"-"`
+}
+
+func newValidation() *Validation {
+	return &Validation{Errors: make(map[string]string)}
+}
+
+func TestValidationStruct(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := func() testSignup {
+		age := 30
+		return testSignup{
+			TestBase: TestBase{ID: 1},
+			Email:    "ada@example.com",
+			Password: "lovelace",
+			Confirm:  "lovelace",
+			Role:     "admin",
+			Age:      &age,
+			Address:  testAddress{City: "London", Zip: "12345"},
+			Items:    []testItem{{Name: "engine", Qty: 1}},
+			Tags:     []string{"math", "poetry"},
+			Start:    start,
+			End:      start.Add(time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *testSignup)
+		errors map[string]string
+	}{
+		{name: "valid", modify: func(s *testSignup) {}},
+		{name: "optional fields", modify: func(s *testSignup) {
+			s.Role, s.Age, s.Address.Zip, s.Items, s.Tags, s.End = "", nil, "", nil, nil, time.Time{}
+		}},
+		{name: "required", modify: func(s *testSignup) { s.Email, s.Password, s.Confirm = "", " ", " " },
+			errors: map[string]string{"email": "This field cannot be blank", "password": "This field cannot be blank"}},
+		{name: "email", modify: func(s *testSignup) { s.Email = "ada@" },
+			errors: map[string]string{"email": "Invalid email address"}},
+		{name: "length in characters", modify: func(s *testSignup) { s.Password, s.Confirm = "lövelac", "lövelac" },
+			errors: map[string]string{"password": "This field must be at least 8 characters long"}},
+		{name: "eqfield", modify: func(s *testSignup) { s.Confirm = "babbage1" },
+			errors: map[string]string{"confirm": "This field must match Password"}},
+		{name: "oneof", modify: func(s *testSignup) { s.Role = "root" },
+			errors: map[string]string{"role": "This field must be one of admin user"}},
+		{name: "pointer", modify: func(s *testSignup) { age := 16; s.Age = &age },
+			errors: map[string]string{"age": "This field must be at least 18"}},
+		{name: "nested struct", modify: func(s *testSignup) { s.Address = testAddress{Zip: "1234"} },
+			errors: map[string]string{"address.city": "This field cannot be blank", "address.zip": "This field must be exactly 5 characters long"}},
+		{name: "first failing rule only", modify: func(s *testSignup) { s.Address.Zip = "abcde" },
+			errors: map[string]string{"address.zip": "This field must be a number"}},
+		{name: "nil struct pointer", modify: func(s *testSignup) { s.Billing = nil }},
+		{name: "struct pointer", modify: func(s *testSignup) { s.Billing = &testAddress{} },
+			errors: map[string]string{"billing.city": "This field cannot be blank"}},
+		{name: "slice of structs", modify: func(s *testSignup) { s.Items = []testItem{{Name: "engine"}, {Qty: 2}} },
+			errors: map[string]string{"items[0].qty": "This field must be at least 1", "items[1].name": "This field cannot be blank"}},
+		{name: "slice size", modify: func(s *testSignup) { s.Items = make([]testItem, 3) },
+			errors: map[string]string{"items": "This field must contain at most 2 items"}},
+		{name: "dive", modify: func(s *testSignup) { s.Tags = []string{"math", "not ok"} },
+			errors: map[string]string{"tags[1]": "This field must only contain letters and digits"}},
+		{name: "gtfield on times", modify: func(s *testSignup) { s.End = start.Add(-time.Hour) },
+			errors: map[string]string{"end": "This field must be greater than Start"}},
+		{name: "embedded struct", modify: func(s *testSignup) { s.ID = 0 },
+			errors: map[string]string{"id": "This field must be at least 1"}},
+		{name: "form name", modify: func(s *testSignup) { s.Nickname = "ada l" },
+			errors: map[string]string{"nickname": "Spaces are not permitted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			v := newValidation()
+			v.Struct(&s)
+			if len(v.Errors) != len(tt.errors) {
+				t.Fatalf("Errors = %v, want %v", v.Errors, tt.errors)
+			}
+			for field, message := range tt.errors {
+				if v.Errors[field] != message {
+					t.Errorf("Errors[%s] = %q, want %q", field, v.Errors[field], message)
+				}
+			}
+			if v.Valid() != (len(tt.errors) == 0) {
+				t.Errorf("Valid = %v with errors %v", v.Valid(), v.Errors)
+			}
+		})
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	rules := map[string]string{
+		"email":    "required,email",
+		"age":      "omitempty,int,min=18",
+		"password": "required,min=8",
+		"confirm":  "eqfield=password",
+		"tags":     "max=2,dive,alpha",
+	}
+	tests := []struct {
+		name   string
+		data   url.Values
+		errors map[string]string
+	}{
+		{name: "valid", data: url.Values{"email": {"ada@example.com"}, "age": {"36"}, "password": {"lovelace"}, "confirm": {"lovelace"}, "tags": {"math", "poetry"}}},
+		{name: "missing", data: url.Values{},
+			errors: map[string]string{"email": "This field cannot be blank", "password": "This field cannot be blank"}},
+		// the size of a numeric field is its value, not its length
+		{name: "number", data: url.Values{"email": {"ada@example.com"}, "age": {"9"}, "password": {"lovelace"}, "confirm": {"lovelace"}},
+			errors: map[string]string{"age": "This field must be at least 18"}},
+		{name: "not a number", data: url.Values{"email": {"ada@example.com"}, "age": {"old"}, "password": {"lovelace"}, "confirm": {"lovelace"}},
+			errors: map[string]string{"age": "This field must be an integer"}},
+		{name: "values", data: url.Values{"email": {"ada@example.com"}, "password": {"lovelace"}, "confirm": {"babbage1"}, "tags": {"math", "42"}},
+			errors: map[string]string{"confirm": "This field must match password", "tags[1]": "This field must only contain letters"}},
+		{name: "too many values", data: url.Values{"email": {"ada@example.com"}, "password": {"lovelace"}, "confirm": {"lovelace"}, "tags": {"a", "b", "c"}},
+			errors: map[string]string{"tags": "This field must contain at most 2 items"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newValidation()
+			v.Data = tt.data
+			v.Rules(rules)
+			if len(v.Errors) != len(tt.errors) {
+				t.Fatalf("Errors = %v, want %v", v.Errors, tt.errors)
+			}
+			for field, message := range tt.errors {
+				if v.Errors[field] != message {
+					t.Errorf("Errors[%s] = %q, want %q", field, v.Errors[field], message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationLocalize(t *testing.T) {
+	b := i18n.NewBundle("en")
+	b.AddMessages("fr", map[string]string{
+		"validation.required": "Le champ {field} est obligatoire",
+		"validation.min_len":  "Au moins {param} caractères",
+	})
+	v := newValidation()
+	v.Localize(i18n.NewContext(context.Background(), b.Localizer("fr")))
+
+	v.Struct(struct {
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"min=8"`
+		Name     string `json:"name" validate:"max=3"`
+	}{Password: "short", Name: "Ada Lovelace"})
+
+	want := map[string]string{
+		"email":    "Le champ email est obligatoire",
+		"password": "Au moins 8 caractères",
+		// no translation
+		"name": "This field must be at most 3 characters long",
+	}
+	for field, message := range want {
+		if v.Errors[field] != message {
+			t.Errorf("Errors[%s] = %q, want %q", field, v.Errors[field], message)
+		}
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	slug := regexp.MustCompile(`^[a-z0-9-]+$`)
+	RegisterRule("slug", "Only lowercase letters, digits and dashes", func(f FieldValue) bool {
+		return slug.MatchString(f.String())
+	})
+	t.Cleanup(func() {
+		rulesMu.Lock()
+		delete(rules, "slug")
+		rulesMu.Unlock()
+	})
+
+	tests := []struct {
+		slug  string
+		valid bool
+	}{
+		{"hello-world", true},
+		{"Hello World", false},
+		{"", true}, // omitempty
+	}
+	for _, tt := range tests {
+		v := newValidation()
+		v.Struct(struct {
+			Slug string `validate:"omitempty,slug"`
+		}{tt.slug})
+		if v.Valid() != tt.valid {
+			t.Errorf("slug %q: Errors = %v, want valid = %v", tt.slug, v.Errors, tt.valid)
+		}
+		if !tt.valid && v.Errors["Slug"] != "Only lowercase letters, digits and dashes" {
+			t.Errorf("slug %q: message = %q", tt.slug, v.Errors["Slug"])
+		}
+	}
+}
+
+func TestValidationTagErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    any
+	}{
+		{"unknown rule", &struct {
+			A string `validate:"required,unknown"`
+		}{}},
+		{"invalid size", &struct {
+			A string `validate:"min=three"`
+		}{A: "a"}},
+		{"unknown field", &struct {
+			A string `validate:"eqfield=B"`
+		}{}},
+		{"not a struct", new(int)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			newValidation().Struct(tt.s)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package socle
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -8,18 +9,54 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/socle-framework/socle/pkg/i18n"
 )
 
 type Validation struct {
 	Data   url.Values
 	Errors map[string]string
+
+	localizer *i18n.Localizer
 }
 
+// Validator returns a validation of data, with the messages of I18N_DEFAULT. See Localize
+// for the messages of the locale of a request.
 func (c *Socle) Validator(data url.Values) *Validation {
-	return &Validation{
+	v := &Validation{
 		Errors: make(map[string]string),
 		Data:   data,
 	}
+	if c.I18n != nil {
+		v.localizer = c.I18n.Localizer(c.I18n.Fallback())
+	}
+	return v
+}
+
+// Localize translates the messages of v to the locale of ctx, the one negotiated by the
+// i18n middleware:
+//
+//	v := app.Validator(r.PostForm).Localize(r.Context())
+func (v *Validation) Localize(ctx context.Context) *Validation {
+	if l, ok := i18n.FromContext(ctx); ok {
+		v.localizer = l
+	}
+	return v
+}
+
+// message returns the validation.<key> message of the locale of v, else message, with the
+// param and field parameters.
+func (v *Validation) message(key, message, param, field string) string {
+	if v.localizer != nil {
+		if m, ok := v.localizer.Bundle.Lookup(v.localizer.Locale, "validation."+key); ok {
+			message = m
+		}
+	}
+	return i18n.Format(message, "param", param, "field", field)
+}
+
+// fail adds the error of the rule key to field.
+func (v *Validation) fail(field, key string) {
+	v.AddError(field, v.message(key, ruleMessages[key], "", field))
 }
 
 func (v *Validation) Valid() bool {
@@ -32,23 +69,28 @@ func (v *Validation) AddError(key, message string) {
 	}
 }
 
+// Has reports whether field has a value in Data, or in the form of r when Data is nil.
 func (v *Validation) Has(field string, r *http.Request) bool {
-	x := r.Form.Get(field)
-	if x == "" {
-		return false
-	}
-	return true
+	return v.value(field, r) != ""
 }
 
+// Required adds an error for each of fields without a value in Data, or in the form of r
+// when Data is nil.
 func (v *Validation) Required(r *http.Request, fields ...string) {
 	for _, field := range fields {
-		value := r.Form.Get(field)
-		if strings.TrimSpace(value) == "" {
-			v.AddError(field, "This field cannot be blank")
+		if strings.TrimSpace(v.value(field, r)) == "" {
+			v.fail(field, "required")
 		}
 	}
 }
 
+func (v *Validation) value(field string, r *http.Request) string {
+	if v.Data == nil && r != nil {
+		return r.Form.Get(field)
+	}
+	return v.Data.Get(field)
+}
+
 func (v *Validation) Check(ok bool, key, message string) {
 	if !ok {
 		v.AddError(key, message)
@@ -57,33 +99,33 @@ func (v *Validation) Check(ok bool, key, message string) {
 
 func (v *Validation) IsEmail(field, value string) {
 	if !govalidator.IsEmail(value) {
-		v.AddError(field, "Invalid email address")
+		v.fail(field, "email")
 	}
 }
 
 func (v *Validation) IsInt(field, value string) {
 	_, err := strconv.Atoi(value)
 	if err != nil {
-		v.AddError(field, "This field must be an integer")
+		v.fail(field, "int")
 	}
 }
 
 func (v *Validation) IsFloat(field, value string) {
 	_, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		v.AddError(field, "This field must be a floating point number")
+		v.fail(field, "float")
 	}
 }
 
 func (v *Validation) IsDateISO(field, value string) {
 	_, err := time.Parse("2006-01-02", value)
 	if err != nil {
-		v.AddError(field, "This field must be a date in the form of YYYY-MM-DD")
+		v.fail(field, "date")
 	}
 }
 
 func (v *Validation) NoSpaces(field, value string) {
 	if govalidator.HasWhitespace(value) {
-		v.AddError(field, "Spaces are not permitted")
+		v.fail(field, "nospaces")
 	}
 }